@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegramtest"
+)
+
+// runBotEnv — если переменная задана, тестовый бинарник работает как бот.
+// Так сквозной тест запускает настоящий main отдельным процессом.
+const runBotEnv = "DENTAL_BOT_E2E_RUN_MAIN"
+
+const (
+	e2eToken       = "123456:e2e"
+	e2eGroupChatID = -100
+	e2eAdminID     = 1
+	e2ePatientID   = 2
+	e2eTimeout     = 10 * time.Second
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runBotEnv) != "" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// botCommand готовит запуск тестового бинарника как бота с аргументами args
+func botCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), runBotEnv+"=1")
+	return cmd
+}
+
+// startBot запускает бота против заглушки Bot API и останавливает его по завершении
+// теста. Бот пишет переписку в record.jsonl в возвращаемом каталоге.
+func startBot(t *testing.T, api *telegramtest.Server) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	var logs bytes.Buffer
+	cmd := botCommand(dir)
+	cmd.Stderr = &logs
+	cmd.Env = append(cmd.Env,
+		"TELEGRAM_BOT_TOKEN="+e2eToken,
+		"TELEGRAM_API_URL="+api.URL,
+		"ADMIN_GROUP_CHAT_ID="+strconv.Itoa(e2eGroupChatID),
+		"ADMIN_USER_IDS="+strconv.Itoa(e2eAdminID),
+		"DATABASE_PATH="+filepath.Join(dir, "clinic.db"),
+		"DAILY_DIGEST_HOUR=off",
+		"UPDATE_MODE=polling",
+		"HTTP_ADDR=",
+		"METRICS_ADDR=",
+		"LOG_LEVEL=debug",
+		"RECORD_FILE="+filepath.Join(dir, "record.jsonl"),
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		cmd.Process.Signal(syscall.SIGTERM)
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("bot exited with %v", err)
+		}
+		if t.Failed() {
+			t.Logf("bot log:\n%s", logs.String())
+		}
+	})
+	return dir
+}
+
+func TestEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts the bot as a separate process")
+	}
+
+	// Заглушка закрывается после остановки бота: Cleanup выполняются в обратном порядке
+	api := telegramtest.NewServer(e2eToken)
+	t.Cleanup(api.Close)
+	dir := startBot(t, api)
+
+	date := nextMonday(time.Now()).Format("2006-01-02")
+	patient := []telegramtest.Step{
+		{Text: "Здравствуйте", Expect: "Выберите действие"},
+		{Text: "🗓️ Записаться на приём", Expect: "Как вас зовут?"},
+		{Text: "Иван Петров", Expect: "номер телефона"},
+		{Text: "+7 999 123-45-67", Expect: "Какую услугу"},
+		{Text: "Чистка", Expect: "На какую дату"},
+		{Text: date, Expect: "Выберите удобное время"},
+		{Press: "time:" + date + " 10:00", Expect: "Спасибо за запись"},
+	}
+	if err := api.Play(e2ePatientID, e2ePatientID, patient, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, _, err := api.WaitFor(0, "sendMessage", "Новая запись на приём", e2eTimeout); err != nil {
+		t.Fatal(err)
+	} else if r.ChatID() != e2eGroupChatID {
+		t.Errorf("admin notification sent to chat %d", r.ChatID())
+	}
+	if _, _, err := api.WaitFor(0, "sendDocument", "календарь", e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	admin := []telegramtest.Step{
+		{Text: "/admin_list", Expect: "Иван Петров"},
+		{Press: "card:1", Expect: "Телефон: +7 999 123-45-67"},
+		{Press: "delete:1", Method: "answerCallbackQuery", Expect: "Заявка отменена"},
+		{Text: "/admin_find петров", Expect: "Отменена"},
+	}
+	if err := api.Play(e2eAdminID, e2eAdminID, admin, e2eTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// Запись этой же переписки проигрывается без расхождений
+	replayCmd := botCommand(dir, "replay", "-in", "record.jsonl",
+		"-admins", strconv.Itoa(e2eAdminID), "-group", strconv.Itoa(e2eGroupChatID))
+	out, err := replayCmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "Replayed 11 updates, 0 differ") {
+		t.Fatalf("replay failed: %v\n%s", err, out)
+	}
+}
+
+// nextMonday — ближайший понедельник после now: рабочий день, всё время которого
+// ещё впереди
+func nextMonday(now time.Time) time.Time {
+	days := (int(time.Monday-now.Weekday())+6)%7 + 1
+	return now.AddDate(0, 0, days)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/export"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+// runExport реализует подкоманду "export": выгрузку заявок из базы без запуска бота
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	from := fs.String("from", "", "first appointment date, YYYY-MM-DD")
+	to := fs.String("to", "", "last appointment date, YYYY-MM-DD")
+	format := fs.String("format", export.FormatCSV, "output format: csv or xlsx")
+	dbPath := fs.String("db", "clinic.db", "SQLite database file")
+	out := fs.String("out", "", "output file, \"-\" for stdout (default: bookings_<period>.<format>)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := repository.InitDB(*dbPath, slog.Default())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	bookings, err := service.NewBookingService(db, slog.Default()).ExportBookings(*from, *to)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	path := *out
+	if path == "" {
+		path = export.FileName(*from, *to, *format)
+	}
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := export.Write(w, *format, bookings); err != nil {
+		return err
+	}
+	if path != "-" {
+		fmt.Fprintf(os.Stderr, "Exported %d bookings to %s\n", len(bookings), path)
+	}
+	return nil
+}
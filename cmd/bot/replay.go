@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/config"
+	"github.com/REmakerzz/dental-clinic-bot/internal/handler"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/replay"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+// runReplay реализует подкоманду "replay": проигрывает запись RECORD_FILE через
+// обработчики на временной базе и показывает, чем ответы отличаются от записанных
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	in := fs.String("in", "", "recorded JSONL file (RECORD_FILE)")
+	dbPath := fs.String("db", "", "database snapshot to start from; it is copied, not modified (default: empty database)")
+	admins := fs.String("admins", os.Getenv("ADMIN_USER_IDS"), "comma-separated owner user IDs (ADMIN_USER_IDS)")
+	group := fs.Int64("group", envInt64("ADMIN_GROUP_CHAT_ID"), "admin group chat ID")
+	groupRole := fs.String("group-role", model.RoleAdmin, "role of admin group members in the group, empty for none")
+	verbose := fs.Bool("v", false, "print every update, not only the ones that differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	entries, err := replay.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+
+	cfg := &config.Config{AdminGroupChatID: *group, WaitlistClaimTTL: config.DefaultWaitlistClaimTTL}
+	if *admins != "" {
+		if cfg.AdminUserIDs, err = config.ParseIDList(*admins); err != nil {
+			return err
+		}
+	}
+
+	scratch := repository.InMemory
+	if *dbPath != "" {
+		dir, err := os.MkdirTemp("", "dental-replay-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		scratch = filepath.Join(dir, "clinic.db")
+		if err := copyFile(*dbPath, scratch); err != nil {
+			return err
+		}
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+	db, err := repository.InitDB(scratch, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	sender := &replay.Capture{}
+	bookingService := service.NewBookingService(db, logger)
+	doctorService := service.NewDoctorService(db)
+	patientService := service.NewPatientService(db, logger)
+	waitlistService := service.NewWaitlistService(db, cfg.WaitlistClaimTTL, logger)
+	accessService := service.NewAccessService(db, cfg.AdminUserIDs, service.GroupAccess{
+		ChatID:  cfg.AdminGroupChatID,
+		Role:    *groupRole,
+		Members: everyoneIsMember{},
+	}, logger)
+	// Обновление обрабатывается на момент, когда оно было записано: иначе бот
+	// предложил бы другое свободное время
+	var now time.Time
+	clock := func() time.Time { return now }
+	sessions := make(handler.Sessions)
+	commands := handler.NewCommandHandler(sender, cfg.AdminGroupChatID, bookingService, doctorService, patientService, accessService, sessions, cfg).WithClock(clock)
+	callbacks := handler.NewCallbackHandler(sender, bookingService, doctorService, patientService, waitlistService, accessService, cfg, sessions).WithClock(clock)
+
+	exchanges := replay.Exchanges(entries)
+	differ := 0
+	for _, ex := range exchanges {
+		now = ex.Time
+		ctx := context.Background()
+		switch {
+		case ex.Update.Message != nil:
+			commands.HandleMessage(ctx, ex.Update.Message)
+		case ex.Update.CallbackQuery != nil:
+			callbacks.HandleCallback(ctx, ex.Update.CallbackQuery)
+		}
+
+		diff := replay.Diff(ex.Expected, sender.Take())
+		if len(diff) > 0 {
+			differ++
+		}
+		if len(diff) > 0 || *verbose {
+			fmt.Println(describeUpdate(ex.Update))
+			for _, line := range diff {
+				fmt.Println("  " + line)
+			}
+		}
+	}
+
+	fmt.Printf("Replayed %d updates, %d differ\n", len(exchanges), differ)
+	if differ > 0 {
+		return fmt.Errorf("%d of %d updates produced different replies", differ, len(exchanges))
+	}
+	return nil
+}
+
+// everyoneIsMember считает участником группы любого, кто в ней писал: ответов
+// getChatMember в записи нет
+type everyoneIsMember struct{}
+
+func (everyoneIsMember) IsMember(chatID, userID int64) (bool, error) {
+	return true, nil
+}
+
+func describeUpdate(u tgbotapi.Update) string {
+	s := "update " + strconv.Itoa(u.UpdateID)
+	switch {
+	case u.Message != nil:
+		s += fmt.Sprintf(" message chat=%d: %q", u.Message.Chat.ID, u.Message.Text)
+	case u.CallbackQuery != nil:
+		s += fmt.Sprintf(" callback user=%d: %q", u.CallbackQuery.From.ID, u.CallbackQuery.Data)
+	}
+	return s
+}
+
+func envInt64(key string) int64 {
+	n, _ := strconv.ParseInt(os.Getenv(key), 10, 64)
+	return n
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -3,16 +3,24 @@ package app
 import (
 	"context"
 	"database/sql"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/REmakerzz/dental-clinic-bot/internal/config"
 	"github.com/REmakerzz/dental-clinic-bot/internal/handler"
-	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
+	"github.com/REmakerzz/dental-clinic-bot/internal/outbox"
+	"github.com/REmakerzz/dental-clinic-bot/internal/replay"
 	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
 	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+	"github.com/REmakerzz/dental-clinic-bot/internal/web"
 )
 
 type App struct {
@@ -21,7 +29,18 @@ type App struct {
 	callbackHandler *handler.CallbackHandler
 	config          *config.Config
 	db              *sql.DB
-	userBookings    map[int64]*model.Booking
+	userBookings    handler.Sessions
+	httpServer      *web.Server
+	metricsServer   *web.Server
+	webhookUpdates  chan tgbotapi.Update
+	logger          *slog.Logger
+	recorder        *replay.Recorder
+	dispatcher      *outbox.Dispatcher
+	threads         *telegram.Threads
+	members         *telegram.Members
+	// digests и offers — обработчики фоновых рассылок с клиентом с повторами
+	digests *handler.CommandHandler
+	offers  *handler.CallbackHandler
 }
 
 func New() (*App, error) {
@@ -31,28 +50,114 @@ func New() (*App, error) {
 		return nil, err
 	}
 
+	// Init logger; the standard log package is redirected to it as well
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+
 	// Init DB
-	db := repository.InitDB()
+	db, err := repository.InitDB(cfg.DatabasePath, logger)
+	if err != nil {
+		return nil, err
+	}
 	// Не закрываем здесь — defer в main.go (или можно использовать App.Close позже)
 
 	// Init services
-	bookingService := service.NewBookingService(db)
+	bookingService := service.NewBookingService(db, logger)
+	doctorService := service.NewDoctorService(db)
+	patientService := service.NewPatientService(db, logger)
+	waitlistService := service.NewWaitlistService(db, cfg.WaitlistClaimTTL, logger)
 
-	// Init bot
-	bot, err := tgbotapi.NewBotAPI(cfg.TelegramToken)
+	// Init bot; the HTTP client reports Bot API latency to metrics. The library's own
+	// log goes through our logger so that the bot token is cut out of it.
+	tgbotapi.SetLogger(telegram.NewBotLogger(logger))
+	client := &http.Client{Transport: metrics.InstrumentTransport(http.DefaultTransport)}
+	bot, err := tgbotapi.NewBotAPIWithClient(cfg.TelegramToken, cfg.TelegramAPIURL+"/bot%s/%s", client)
 	if err != nil {
-		return nil, err
+		return nil, telegram.RedactToken(err)
+	}
+
+	cfg.BotUserName = bot.Self.UserName
+	logger.Info("bot authorized", "username", bot.Self.UserName,
+		"admin_group", cfg.AdminGroupChatID, "admin_group_role", cfg.AdminGroupRole,
+		"owners", cfg.AdminUserIDs, "update_mode", cfg.UpdateMode)
+
+	// Background sends retry and wait out rate limits; replies to updates share the
+	// limits but fail fast, since updates are handled one at a time
+	telegramClient := telegram.NewClient(bot, logger)
+	replyClient := telegramClient.Interactive()
+
+	// Members of the admin group get the group role for commands sent there
+	members := telegram.NewMembers(replyClient, memberCacheTTL)
+	accessService := service.NewAccessService(db, cfg.AdminUserIDs, service.GroupAccess{
+		ChatID:  cfg.AdminGroupChatID,
+		Role:    cfg.AdminGroupRole,
+		Members: members,
+	}, logger)
+
+	// Handlers send through the reply client and keep replies in the forum topic
+	// of the command, optionally recording the conversation for the replay subcommand
+	threads := telegram.NewThreads(replyClient, cfg.AdminGroupChatID, cfg.AdminGroupThreadID)
+	var sender handler.Sender = threads
+	var recorder *replay.Recorder
+	if cfg.RecordFile != "" {
+		recorder, err = replay.Open(cfg.RecordFile)
+		if err != nil {
+			return nil, err
+		}
+		sender = recorder.Wrap(sender)
+		logger.Warn("recording updates and replies with personal data", "file", cfg.RecordFile)
 	}
 
-	log.Printf("✅ Bot authorized as @%s", bot.Self.UserName)
-	log.Printf("📦 AdminGroup: %d | Admins: %v", cfg.AdminGroupChatID, cfg.AdminUserIDs)
+	// Staff notifications, the daily digest and waitlist offers are not part of the
+	// conversation, so they bypass the recorder and go through the retrying client.
+	// They run alongside update handling and need their own Threads.
+	background := telegram.NewThreads(telegramClient, cfg.AdminGroupChatID, cfg.AdminGroupThreadID)
+	dispatcher := outbox.NewDispatcher(db, background, logger)
 
 	// Create shared userBookings map
-	userBookings := make(map[int64]*model.Booking)
+	userBookings := make(handler.Sessions)
 
 	// Init handlers
-	commandHandler := handler.NewCommandHandler(bot, cfg.AdminGroupChatID, bookingService, userBookings, cfg)
-	callbackHandler := handler.NewCallbackHandler(bot, bookingService, cfg, userBookings)
+	commandHandler := handler.NewCommandHandler(sender, cfg.AdminGroupChatID, bookingService, doctorService, patientService, accessService, userBookings, cfg)
+	callbackHandler := handler.NewCallbackHandler(sender, bookingService, doctorService, patientService, waitlistService, accessService, cfg, userBookings)
+
+	// Init HTTP server
+	var httpServer *web.Server
+	if cfg.HTTPAddr != "" {
+		httpServer = web.NewServer(cfg.HTTPAddr, cfg.TLSCertFile, cfg.TLSKeyFile, logger)
+		httpServer.Handle(web.CalendarFeedPath, web.CalendarFeedHandler(doctorService, logger))
+		httpServer.Handle("GET /healthz", web.HealthHandler())
+		httpServer.Handle("GET /readyz", web.ReadyHandler(logger, databaseCheck(db), telegramCheck(bot)))
+	}
+
+	// Metrics are served on their own internal listener, never next to the public
+	// calendar feeds and webhook
+	var metricsServer *web.Server
+	if cfg.MetricsAddr != "" {
+		metricsServer = web.NewServer(cfg.MetricsAddr, "", "", logger)
+		metricsServer.Handle("GET /metrics", metrics.Handler())
+	}
+
+	// Webhook mode receives updates through the HTTP server, polling needs the webhook removed
+	var webhookUpdates chan tgbotapi.Update
+	if cfg.UpdateMode == config.UpdateModeWebhook {
+		path, err := webhookPath(cfg.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		webhookUpdates = make(chan tgbotapi.Update, webhookQueueSize)
+		httpServer.Handle("POST "+path, web.WebhookHandler(cfg.WebhookSecret, webhookUpdates, logger))
+
+		if err := setWebhook(bot, cfg); err != nil {
+			return nil, err
+		}
+		logger.Info("webhook set", "url", cfg.WebhookURL)
+	} else if err := deleteWebhook(bot); err != nil {
+		return nil, err
+	}
 
 	return &App{
 		bot:             bot,
@@ -61,34 +166,137 @@ func New() (*App, error) {
 		config:          cfg,
 		db:              db,
 		userBookings:    userBookings,
+		httpServer:      httpServer,
+		metricsServer:   metricsServer,
+		webhookUpdates:  webhookUpdates,
+		logger:          logger,
+		recorder:        recorder,
+		dispatcher:      dispatcher,
+		threads:         threads,
+		members:         members,
+		digests:         commandHandler.WithSender(background),
+		offers:          callbackHandler.WithSender(background),
 	}, nil
 }
 
 func (a *App) Run(ctx context.Context) {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	var updates <-chan tgbotapi.Update
+	if a.webhookUpdates != nil {
+		updates = a.webhookUpdates
+	} else {
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		u.AllowedUpdates = allowedUpdates
+
+		updates = a.bot.GetUpdatesChan(u)
+		defer a.bot.StopReceivingUpdates()
+	}
 
-	updates := a.bot.GetUpdatesChan(u)
+	if a.httpServer != nil {
+		go a.httpServer.Run(ctx)
+	}
+	if a.metricsServer != nil {
+		go a.metricsServer.Run(ctx)
+	}
+
+	// Background sends retry with pauses, so they run outside the update loop
+	go a.dispatcher.Run(ctx)
+	if a.config.DailyDigestHour >= 0 {
+		go a.runDailyDigest(ctx)
+	}
+	go a.runWaitlist(ctx)
 
 	for {
 		select {
 		case update := <-updates:
-			if update.Message != nil {
-				a.commandHandler.HandleMessage(update.Message)
-			} else if update.CallbackQuery != nil {
-				a.callbackHandler.HandleCallback(update.CallbackQuery)
-			}
+			a.dispatch(ctx, update)
 		case <-ctx.Done():
-			log.Println("🔌 Shutdown signal received. Stopping bot...")
+			a.logger.Info("shutdown signal received, stopping bot")
 			time.Sleep(1 * time.Second)
 			return
 		}
 	}
 }
 
+// dispatch передаёт обновление обработчику; одинаков для polling и webhook
+func (a *App) dispatch(ctx context.Context, update tgbotapi.Update) {
+	logger := a.logger.With(updateAttrs(update)...)
+	ctx = logging.WithLogger(ctx, logger)
+
+	if a.recorder != nil {
+		if err := a.recorder.Update(update); err != nil {
+			logger.Error("failed to record update", "error", err)
+		}
+	}
+
+	// Ответы в админ-группе остаются в теме форума, из которой пришло обновление
+	if chat := update.FromChat(); chat != nil && chat.ID == a.config.AdminGroupChatID {
+		if msg := updateMessage(update); msg != nil {
+			defer a.threads.ReplyTo(msg.MessageID)()
+		}
+	}
+
+	defer func() {
+		// Сбой на одном обновлении не должен останавливать бота
+		if r := recover(); r != nil {
+			logger.Error("panic while handling update", "panic", r, "stack", string(debug.Stack()))
+			metrics.HandlerErrors.WithLabelValues("panic").Inc()
+		}
+		metrics.ActiveBookingSessions.Set(float64(len(a.userBookings)))
+	}()
+
+	switch {
+	case update.Message != nil:
+		if update.Message.IsCommand() {
+			metrics.UpdatesHandled.WithLabelValues("command").Inc()
+		} else {
+			metrics.UpdatesHandled.WithLabelValues("message").Inc()
+		}
+		a.commandHandler.HandleMessage(ctx, update.Message)
+	case update.CallbackQuery != nil:
+		metrics.UpdatesHandled.WithLabelValues("callback").Inc()
+		a.callbackHandler.HandleCallback(ctx, update.CallbackQuery)
+	case update.ChatMember != nil:
+		// Членство изменилось — следующая команда проверит его заново
+		metrics.UpdatesHandled.WithLabelValues("other").Inc()
+		a.members.Forget(update.ChatMember.Chat.ID, update.ChatMember.NewChatMember.User.ID)
+	default:
+		metrics.UpdatesHandled.WithLabelValues("other").Inc()
+	}
+}
+
+// updateMessage возвращает сообщение, на которое отвечает бот: входящее или то,
+// под которым нажата кнопка
+func updateMessage(update tgbotapi.Update) *tgbotapi.Message {
+	switch {
+	case update.Message != nil:
+		return update.Message
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.Message
+	default:
+		return nil
+	}
+}
+
+// updateAttrs — поля лога, общие для всех записей при обработке обновления
+func updateAttrs(update tgbotapi.Update) []any {
+	attrs := []any{"update_id", update.UpdateID}
+	if chat := update.FromChat(); chat != nil {
+		attrs = append(attrs, "chat_id", chat.ID)
+	}
+	if user := update.SentFrom(); user != nil {
+		attrs = append(attrs, "user_id", user.ID)
+	}
+	return attrs
+}
+
 func (a *App) Close() {
-	log.Println("Closing database...")
+	if a.recorder != nil {
+		a.recorder.Close()
+	}
+
+	a.logger.Info("closing database")
 	if err := a.db.Close(); err != nil {
-		log.Printf("Error closing DB: %v", err)
+		a.logger.Error("failed to close database", "error", err)
 	}
 }
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
+)
+
+// runDailyDigest каждый день в DailyDigestHour публикует расписание на этот день
+// в админ-группе
+func (a *App) runDailyDigest(ctx context.Context) {
+	for {
+		next := nextDigestTime(time.Now(), a.config.DailyDigestHour)
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-timer.C:
+			a.postDigest(ctx, next)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// postDigest публикует расписание на day в админ-группе
+func (a *App) postDigest(ctx context.Context, day time.Time) {
+	logger := a.logger.With("job", "daily_digest", "chat_id", a.config.AdminGroupChatID)
+	if err := a.digests.SendAgenda(logging.WithLogger(ctx, logger), a.config.AdminGroupChatID, day); err != nil {
+		logger.Error("failed to post daily digest", "error", err)
+	}
+}
+
+// nextDigestTime возвращает ближайший момент hour:00 после now
+func nextDigestTime(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
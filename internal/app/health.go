@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+	"github.com/REmakerzz/dental-clinic-bot/internal/web"
+)
+
+// telegramCheckTTL — как долго переиспользовать результат getMe, чтобы частые
+// проверки готовности не расходовали лимиты Bot API
+const telegramCheckTTL = 30 * time.Second
+
+func databaseCheck(db *sql.DB) web.Check {
+	return web.Check{Name: "database", Fn: db.PingContext}
+}
+
+func telegramCheck(bot *tgbotapi.BotAPI) web.Check {
+	var mu sync.Mutex
+	var checkedAt time.Time
+	var lastErr error
+
+	return web.Check{Name: "telegram", Fn: func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if time.Since(checkedAt) < telegramCheckTTL {
+			return lastErr
+		}
+
+		// GetMe не принимает контекст, поэтому ждём его не дольше ctx
+		done := make(chan error, 1)
+		go func() {
+			_, err := bot.GetMe()
+			done <- telegram.RedactToken(err)
+		}()
+
+		select {
+		case err := <-done:
+			checkedAt, lastErr = time.Now(), err
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/config"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+// webhookQueueSize — сколько обновлений может ждать обработки, пока Telegram держит запрос
+const webhookQueueSize = 100
+
+// allowedUpdates — типы обновлений, которые обрабатывает бот. chat_member
+// приходит, только если бот — администратор админ-группы.
+var allowedUpdates = []string{"message", "callback_query", "chat_member"}
+
+// memberCacheTTL — сколько помнить, состоит ли пользователь в админ-группе
+const memberCacheTTL = 5 * time.Minute
+
+// setWebhook регистрирует адрес webhook в Telegram вместе с секретом для заголовка
+func setWebhook(bot *tgbotapi.BotAPI, cfg *config.Config) error {
+	params := tgbotapi.Params{
+		"url":          cfg.WebhookURL,
+		"secret_token": cfg.WebhookSecret,
+	}
+	if err := params.AddInterface("allowed_updates", allowedUpdates); err != nil {
+		return err
+	}
+
+	var resp *tgbotapi.APIResponse
+	var err error
+	if cfg.WebhookSelfSigned {
+		resp, err = bot.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(cfg.TLSCertFile),
+		}})
+	} else {
+		resp, err = bot.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		return fmt.Errorf("setWebhook: %w", telegram.RedactToken(err))
+	}
+	if !resp.Ok {
+		return fmt.Errorf("setWebhook: %s", resp.Description)
+	}
+
+	return nil
+}
+
+// deleteWebhook снимает webhook: пока он установлен, getUpdates не работает
+func deleteWebhook(bot *tgbotapi.BotAPI) error {
+	_, err := bot.Request(tgbotapi.DeleteWebhookConfig{})
+	return telegram.RedactToken(err)
+}
+
+// webhookPath — путь, по которому встроенный сервер принимает обновления
+func webhookPath(webhookURL string) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Path == "" {
+		return "/", nil
+	}
+	return u.Path, nil
+}
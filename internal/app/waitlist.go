@@ -0,0 +1,35 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
+)
+
+// waitlistInterval — как часто искать освободившееся время для листа ожидания
+const waitlistInterval = time.Minute
+
+// runWaitlist каждые waitlistInterval предлагает освободившееся время пациентам
+// из листа ожидания
+func (a *App) runWaitlist(ctx context.Context) {
+	ticker := time.NewTicker(waitlistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			a.offerFreedSlots(ctx, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// offerFreedSlots предлагает освободившееся время пациентам из листа ожидания
+func (a *App) offerFreedSlots(ctx context.Context, now time.Time) {
+	logger := a.logger.With("job", "waitlist")
+	if err := a.offers.OfferFreedSlots(logging.WithLogger(ctx, logger), now); err != nil {
+		logger.Error("failed to offer freed slots", "error", err)
+	}
+}
@@ -0,0 +1,120 @@
+// Package calendar формирует файлы iCalendar (RFC 5545) с приёмами
+package calendar
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+const (
+	prodID    = "-//dental-clinic-bot//RU"
+	uidDomain = "dental-clinic-bot"
+	// utcLayout — формат даты-времени iCalendar в UTC
+	utcLayout = "20060102T150405Z"
+	// lineLimit — максимальная длина строки в октетах до переноса
+	lineLimit = 75
+)
+
+// Event — описание события, которое видит владелец календаря
+type Event struct {
+	Booking     *model.Booking
+	Summary     string
+	Description string
+}
+
+// WriteCalendar пишет календарь с событиями. UID события зависит только от ID
+// заявки, а SEQUENCE растёт при каждом изменении, поэтому клиенты обновляют
+// существующие события, а отменённые заявки помечаются STATUS:CANCELLED.
+func WriteCalendar(w io.Writer, name string, events []Event) error {
+	cw := &contentWriter{w: w}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	if name != "" {
+		cw.line("X-WR-CALNAME:" + escape(name))
+	}
+
+	now := time.Now().UTC().Format(utcLayout)
+	for _, e := range events {
+		start, err := time.ParseInLocation("2006-01-02 15:04", e.Booking.DateTime, time.Local)
+		if err != nil {
+			continue
+		}
+
+		cw.line("BEGIN:VEVENT")
+		cw.line("UID:" + UID(e.Booking.ID))
+		cw.line("DTSTAMP:" + now)
+		cw.line("DTSTART:" + start.UTC().Format(utcLayout))
+		cw.line("DTEND:" + start.Add(repository.SlotDuration).UTC().Format(utcLayout))
+		cw.line("SEQUENCE:" + strconv.Itoa(e.Booking.Sequence))
+		cw.line("STATUS:" + eventStatus(e.Booking.Status))
+		if modified, err := time.Parse("2006-01-02 15:04:05", e.Booking.UpdatedAt); err == nil {
+			cw.line("LAST-MODIFIED:" + modified.Format(utcLayout))
+		}
+		cw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			cw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		cw.line("END:VEVENT")
+	}
+
+	cw.line("END:VCALENDAR")
+	return cw.err
+}
+
+// UID — постоянный идентификатор события для заявки
+func UID(bookingID int) string {
+	return "booking-" + strconv.Itoa(bookingID) + "@" + uidDomain
+}
+
+func eventStatus(status string) string {
+	switch status {
+	case model.StatusCancelled:
+		return "CANCELLED"
+	case model.StatusNew:
+		return "TENTATIVE"
+	default:
+		return "CONFIRMED"
+	}
+}
+
+// escape экранирует текстовое значение по RFC 5545, 3.3.11
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// contentWriter пишет строки с CRLF и переносом длинных строк без разрыва UTF-8 символов
+type contentWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (cw *contentWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+
+	var sb strings.Builder
+	limit := lineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		sb.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// строка продолжения начинается с пробела, который тоже считается
+		limit = lineLimit - 1
+	}
+	sb.WriteString(s + "\r\n")
+
+	_, cw.err = io.WriteString(cw.w, sb.String())
+}
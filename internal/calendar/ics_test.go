@@ -0,0 +1,82 @@
+package calendar
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+func TestWriteCalendar(t *testing.T) {
+	description := strings.Repeat("Пациент просил перезвонить накануне. ", 4)
+	var sb strings.Builder
+	err := WriteCalendar(&sb, "Приёмы; врач", []Event{{
+		Booking: &model.Booking{
+			ID:        42,
+			DateTime:  "2030-01-07 10:00",
+			Status:    model.StatusCancelled,
+			Sequence:  3,
+			UpdatedAt: "2030-01-06 12:30:00",
+		},
+		Summary:     `Чистка, пломба; Иван\Петров` + "\nзвонить",
+		Description: description,
+	}, {
+		Booking: &model.Booking{ID: 43, DateTime: "не дата"},
+		Summary: "пропускается",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := sb.String()
+	if !strings.HasSuffix(out, "\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Fatalf("lines must end with CRLF:\n%q", out)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	for _, l := range lines {
+		if len(l) > lineLimit {
+			t.Errorf("line longer than %d octets: %q", lineLimit, l)
+		}
+	}
+
+	// Разворачиваем перенесённые строки (RFC 5545, 3.1)
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	start := time.Date(2030, 1, 7, 10, 0, 0, 0, time.Local).UTC()
+	want := []string{
+		"X-WR-CALNAME:Приёмы\\; врач",
+		"UID:booking-42@dental-clinic-bot",
+		"DTSTART:" + start.Format(utcLayout),
+		"DTEND:" + start.Add(repository.SlotDuration).Format(utcLayout),
+		"SEQUENCE:3",
+		"STATUS:CANCELLED",
+		"LAST-MODIFIED:20300106T123000Z",
+		`SUMMARY:Чистка\, пломба\; Иван\\Петров\nзвонить`,
+		"DESCRIPTION:" + description,
+	}
+	got := strings.Split(unfolded, "\r\n")
+	for _, w := range want {
+		if !slices.Contains(got, w) {
+			t.Errorf("no line %q in:\n%s", w, unfolded)
+		}
+	}
+	if n := strings.Count(unfolded, "BEGIN:VEVENT"); n != 1 {
+		t.Errorf("%d events, want 1: booking with a bad date must be skipped", n)
+	}
+}
+
+func TestEventStatus(t *testing.T) {
+	tests := map[string]string{
+		model.StatusNew:       "TENTATIVE",
+		model.StatusConfirmed: "CONFIRMED",
+		model.StatusCompleted: "CONFIRMED",
+		model.StatusCancelled: "CANCELLED",
+	}
+	for status, want := range tests {
+		if got := eventStatus(status); got != want {
+			t.Errorf("eventStatus(%s) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Package chart рисует простые PNG-графики для статистики без внешних сервисов
+package chart
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	width   = 900
+	height  = 500
+	margin  = 40
+	titleH  = 40
+	textPad = 4
+)
+
+var (
+	background = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	axisColor  = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	textColor  = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	barColor   = color.RGBA{0x2e, 0x86, 0xc1, 0xff}
+)
+
+var (
+	facesOnce sync.Once
+	facesErr  error
+	titleFace font.Face
+	labelFace font.Face
+)
+
+// loadFaces готовит шрифты Go: они покрывают кириллицу и встроены в бинарник
+func loadFaces() error {
+	facesOnce.Do(func() {
+		f, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			facesErr = err
+			return
+		}
+		titleFace, err = opentype.NewFace(f, &opentype.FaceOptions{Size: 16, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			facesErr = err
+			return
+		}
+		labelFace, facesErr = opentype.NewFace(f, &opentype.FaceOptions{Size: 12, DPI: 72, Hinting: font.HintingFull})
+	})
+	return facesErr
+}
+
+// canvas — изображение с заголовком, на котором рисуется график
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(title string) (*canvas, error) {
+	if err := loadFaces(); err != nil {
+		return nil, fmt.Errorf("load font: %w", err)
+	}
+
+	c := &canvas{img: image.NewRGBA(image.Rect(0, 0, width, height))}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	c.text(titleFace, title, margin, titleH-12, textColor)
+	return c, nil
+}
+
+func (c *canvas) rect(x0, y0, x1, y1 int, col color.Color) {
+	draw.Draw(c.img, image.Rect(x0, y0, x1, y1), image.NewUniform(col), image.Point{}, draw.Src)
+}
+
+// text пишет строку; y — базовая линия
+func (c *canvas) text(face font.Face, s string, x, y int, col color.Color) {
+	d := &font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+// textCentered пишет строку с центром в x
+func (c *canvas) textCentered(face font.Face, s string, x, y int, col color.Color) {
+	w := font.MeasureString(face, s).Round()
+	c.text(face, s, x-w/2, y, col)
+}
+
+func (c *canvas) png() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Bars рисует столбчатую диаграмму. Если подписей много, показывается каждая n-я.
+func Bars(title string, labels []string, values []int) ([]byte, error) {
+	c, err := newCanvas(title)
+	if err != nil {
+		return nil, err
+	}
+
+	left, top, right, bottom := margin+30, titleH+10, width-margin, height-margin-10
+	c.rect(left, bottom, right, bottom+1, axisColor)
+	c.rect(left, top, left+1, bottom, axisColor)
+
+	maxValue := maxOf(values)
+	if len(values) == 0 || maxValue == 0 {
+		c.textCentered(labelFace, "Нет данных", (left+right)/2, (top+bottom)/2, textColor)
+		return c.png()
+	}
+
+	c.text(labelFace, fmt.Sprint(maxValue), margin, top+10, textColor)
+	c.text(labelFace, "0", margin+20, bottom, textColor)
+
+	slot := float64(right-left) / float64(len(values))
+	labelEvery := 1
+	for labelWidth := maxLabelWidth(labels) + 2*textPad; float64(labelEvery)*slot < float64(labelWidth); {
+		labelEvery++
+	}
+
+	for i, v := range values {
+		x0 := left + int(float64(i)*slot+slot*0.15)
+		x1 := left + int(float64(i+1)*slot-slot*0.15)
+		if x1 <= x0 {
+			x1 = x0 + 1
+		}
+		barTop := bottom - (bottom-top)*v/maxValue
+		c.rect(x0, barTop, x1, bottom, barColor)
+
+		center := left + int(float64(i)*slot+slot/2)
+		if v > 0 && slot >= 18 {
+			c.textCentered(labelFace, fmt.Sprint(v), center, barTop-textPad, textColor)
+		}
+		if i < len(labels) && i%labelEvery == 0 {
+			c.textCentered(labelFace, labels[i], center, bottom+16, textColor)
+		}
+	}
+
+	return c.png()
+}
+
+// HorizontalBars рисует горизонтальные полосы с подписями слева и долей справа
+func HorizontalBars(title string, labels []string, values []int) ([]byte, error) {
+	c, err := newCanvas(title)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	if total == 0 {
+		c.textCentered(labelFace, "Нет данных", width/2, height/2, textColor)
+		return c.png()
+	}
+
+	labelWidth := maxLabelWidth(labels) + 2*textPad
+	if labelWidth > width/3 {
+		labelWidth = width / 3
+	}
+	left, top, right, bottom := margin+labelWidth, titleH+10, width-margin-90, height-margin
+	rowH := (bottom - top) / len(values)
+	if rowH > 40 {
+		rowH = 40
+	}
+
+	maxValue := maxOf(values)
+	for i, v := range values {
+		y := top + i*rowH
+		c.text(labelFace, truncate(labels[i], labelWidth-textPad), margin, y+rowH/2+5, textColor)
+		c.rect(left, y+rowH/5, left+(right-left)*v/maxValue+1, y+rowH*4/5, barColor)
+		c.text(labelFace, fmt.Sprintf("%d (%.0f%%)", v, float64(v)*100/float64(total)),
+			left+(right-left)*v/maxValue+2*textPad, y+rowH/2+5, textColor)
+	}
+
+	return c.png()
+}
+
+// Heatmap рисует тепловую карту: строки rows, столбцы cols, значения values[row][col]
+func Heatmap(title string, rows, cols []string, values [][]int) ([]byte, error) {
+	c, err := newCanvas(title)
+	if err != nil {
+		return nil, err
+	}
+
+	left, top, right, bottom := margin+30, titleH+10, width-margin, height-margin-10
+	cellW := (right - left) / len(cols)
+	cellH := (bottom - top) / len(rows)
+
+	maxValue := 0
+	for _, row := range values {
+		if m := maxOf(row); m > maxValue {
+			maxValue = m
+		}
+	}
+
+	for r, rowLabel := range rows {
+		y := top + r*cellH
+		c.text(labelFace, rowLabel, margin, y+cellH/2+5, textColor)
+		for col := range cols {
+			x := left + col*cellW
+			v := values[r][col]
+			c.rect(x+1, y+1, x+cellW-1, y+cellH-1, heatColor(v, maxValue))
+			if v > 0 {
+				c.textCentered(labelFace, fmt.Sprint(v), x+cellW/2, y+cellH/2+5, textColor)
+			}
+		}
+	}
+	for col, colLabel := range cols {
+		c.textCentered(labelFace, colLabel, left+col*cellW+cellW/2, bottom+16, textColor)
+	}
+
+	return c.png()
+}
+
+// heatColor переходит от светло-серого (ноль) к насыщенному синему (максимум)
+func heatColor(v, maxValue int) color.Color {
+	if v == 0 || maxValue == 0 {
+		return color.RGBA{0xf2, 0xf2, 0xf2, 0xff}
+	}
+	k := float64(v) / float64(maxValue)
+	mix := func(from, to uint8) uint8 { return uint8(float64(from) + (float64(to)-float64(from))*k) }
+	return color.RGBA{mix(0xd6, 0x1a), mix(0xea, 0x5c), mix(0xf8, 0x9c), 0xff}
+}
+
+func maxOf(values []int) int {
+	m := 0
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+func maxLabelWidth(labels []string) int {
+	w := 0
+	for _, l := range labels {
+		if lw := font.MeasureString(labelFace, l).Round(); lw > w {
+			w = lw
+		}
+	}
+	return w
+}
+
+// truncate укорачивает подпись до ширины в пикселях
+func truncate(s string, maxWidth int) string {
+	if font.MeasureString(labelFace, s).Round() <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 1 && font.MeasureString(labelFace, string(runes)+"…").Round() > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
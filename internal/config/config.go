@@ -2,19 +2,92 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
 )
 
 type Config struct {
-	TelegramToken    string
+	TelegramToken string
+	// Адрес Bot API без пути, например локальный telegram-bot-api или заглушка в тестах
+	TelegramAPIURL   string
 	AdminGroupChatID int64
-	AdminUserIDs     []int64
+	// Роль, которую участник админ-группы получает для команд в самой группе;
+	// пусто — членство в группе прав не даёт
+	AdminGroupRole string
+	// Тема форума админ-группы для уведомлений и расписания; 0 — основная тема
+	AdminGroupThreadID int
+	// Имя бота без @; заполняется при запуске по ответу getMe
+	BotUserName string
+	// Владельцы бота, которые есть всегда; остальные роли выдаются командой /admin_grant
+	AdminUserIDs []int64
+	// Путь к файлу базы данных SQLite
+	DatabasePath string
+	// Час (0-23), в который расписание дня публикуется в админ-группе; -1 — не публиковать
+	DailyDigestHour int
+	// Сколько пациент из листа ожидания может подтверждать предложенное время,
+	// прежде чем оно уйдёт следующему
+	WaitlistClaimTTL time.Duration
+	// Адрес встроенного HTTP-сервера (например, ":8080"); пусто — сервер не запускается
+	HTTPAddr string
+	// Внешний адрес HTTP-сервера для ссылок, которые бот отправляет пользователям
+	PublicURL string
+	// Сертификат и ключ для HTTPS встроенного сервера; пусто — обычный HTTP (например, за reverse proxy)
+	TLSCertFile string
+	TLSKeyFile  string
+	// Внутренний адрес, на котором отдаются метрики Prometheus (например,
+	// "127.0.0.1:9090"); пусто — метрики не публикуются
+	MetricsAddr string
+
+	// Способ получения обновлений: UpdateModePolling или UpdateModeWebhook
+	UpdateMode string
+	// Адрес, на который Telegram отправляет обновления в режиме webhook
+	WebhookURL string
+	// Секрет, который Telegram передаёт в заголовке X-Telegram-Bot-Api-Secret-Token
+	WebhookSecret string
+	// Загрузить TLSCertFile в Telegram при установке webhook (для самоподписанного сертификата)
+	WebhookSelfSigned bool
+
+	// Уровень лога: debug, info, warn или error
+	LogLevel string
+	// Формат лога: text или json
+	LogFormat string
+
+	// Файл JSONL, в который записываются входящие обновления и ответы бота для
+	// подкоманды replay; пусто — не записывать. Запись содержит персональные
+	// данные без маскировки, включать только на время отладки.
+	RecordFile string
 }
 
+const (
+	UpdateModePolling = "polling"
+	UpdateModeWebhook = "webhook"
+)
+
+const defaultDailyDigestHour = 8
+
+// DefaultWaitlistClaimTTL — срок подтверждения предложенного времени, если
+// WAITLIST_CLAIM_MINUTES не задан
+const DefaultWaitlistClaimTTL = 15 * time.Minute
+
+const (
+	defaultTelegramAPIURL = "https://api.telegram.org"
+	defaultDatabasePath   = "clinic.db"
+)
+
+const defaultAdminGroupRole = model.RoleAdmin
+
+const (
+	defaultLogLevel  = "info"
+	defaultLogFormat = "text"
+)
+
 func LoadConfig() (*Config, error) {
 	// Загружаем .env (если есть)
 	_ = godotenv.Load()
@@ -35,19 +108,152 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("ADMIN_USER_IDS is not set")
 	}
 
-	adminIDs := []int64{}
-	for _, idStr := range strings.Split(adminsRaw, ",") {
+	adminIDs, err := ParseIDList(adminsRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	digestHour := defaultDailyDigestHour
+	switch digestStr := strings.TrimSpace(os.Getenv("DAILY_DIGEST_HOUR")); digestStr {
+	case "":
+	case "off":
+		digestHour = -1
+	default:
+		digestHour, err = strconv.Atoi(digestStr)
+		if err != nil || digestHour < -1 || digestHour > 23 {
+			return nil, fmt.Errorf("invalid DAILY_DIGEST_HOUR: %s", digestStr)
+		}
+	}
+
+	groupRole := strings.TrimSpace(os.Getenv("ADMIN_GROUP_ROLE"))
+	switch groupRole {
+	case "":
+		groupRole = defaultAdminGroupRole
+	case "off":
+		groupRole = ""
+	default:
+		// Владельцем можно стать только по ADMIN_USER_IDS или /admin_grant
+		if !model.IsValidRole(groupRole) || groupRole == model.RoleOwner {
+			return nil, fmt.Errorf("invalid ADMIN_GROUP_ROLE: %s", groupRole)
+		}
+	}
+
+	claimTTL := DefaultWaitlistClaimTTL
+	if claimStr := strings.TrimSpace(os.Getenv("WAITLIST_CLAIM_MINUTES")); claimStr != "" {
+		minutes, err := strconv.Atoi(claimStr)
+		if err != nil || minutes < 1 {
+			return nil, fmt.Errorf("invalid WAITLIST_CLAIM_MINUTES: %s", claimStr)
+		}
+		claimTTL = time.Duration(minutes) * time.Minute
+	}
+
+	var threadID int
+	if threadStr := strings.TrimSpace(os.Getenv("ADMIN_GROUP_THREAD_ID")); threadStr != "" {
+		threadID, err = strconv.Atoi(threadStr)
+		if err != nil || threadID < 0 {
+			return nil, fmt.Errorf("invalid ADMIN_GROUP_THREAD_ID: %s", threadStr)
+		}
+	}
+
+	cfg := &Config{
+		TelegramToken:      token,
+		TelegramAPIURL:     strings.TrimRight(os.Getenv("TELEGRAM_API_URL"), "/"),
+		AdminGroupChatID:   groupChatID,
+		AdminGroupRole:     groupRole,
+		AdminGroupThreadID: threadID,
+		AdminUserIDs:       adminIDs,
+		DatabasePath:       os.Getenv("DATABASE_PATH"),
+		DailyDigestHour:    digestHour,
+		WaitlistClaimTTL:   claimTTL,
+		HTTPAddr:           os.Getenv("HTTP_ADDR"),
+		PublicURL:          strings.TrimRight(os.Getenv("PUBLIC_URL"), "/"),
+		TLSCertFile:        os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:         os.Getenv("TLS_KEY_FILE"),
+		MetricsAddr:        os.Getenv("METRICS_ADDR"),
+		UpdateMode:         os.Getenv("UPDATE_MODE"),
+		WebhookURL:         os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:      os.Getenv("WEBHOOK_SECRET"),
+		LogLevel:           os.Getenv("LOG_LEVEL"),
+		LogFormat:          os.Getenv("LOG_FORMAT"),
+		RecordFile:         os.Getenv("RECORD_FILE"),
+	}
+
+	if cfg.TelegramAPIURL == "" {
+		cfg.TelegramAPIURL = defaultTelegramAPIURL
+	} else if u, err := url.Parse(cfg.TelegramAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("TELEGRAM_API_URL must be an http:// or https:// URL")
+	}
+	if cfg.DatabasePath == "" {
+		cfg.DatabasePath = defaultDatabasePath
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = defaultLogLevel
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = defaultLogFormat
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.MetricsAddr != "" && cfg.MetricsAddr == cfg.HTTPAddr {
+		return nil, fmt.Errorf("METRICS_ADDR must differ from HTTP_ADDR")
+	}
+
+	if selfSigned := os.Getenv("WEBHOOK_SELF_SIGNED"); selfSigned != "" {
+		cfg.WebhookSelfSigned, err = strconv.ParseBool(selfSigned)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOK_SELF_SIGNED: %w", err)
+		}
+	}
+
+	switch cfg.UpdateMode {
+	case "":
+		cfg.UpdateMode = UpdateModePolling
+	case UpdateModePolling:
+	case UpdateModeWebhook:
+		if err := validateWebhook(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid UPDATE_MODE: %s", cfg.UpdateMode)
+	}
+
+	return cfg, nil
+}
+
+// ParseIDList разбирает список Telegram ID через запятую, как в ADMIN_USER_IDS
+func ParseIDList(s string) ([]int64, error) {
+	ids := []int64{}
+	for _, idStr := range strings.Split(s, ",") {
 		idStr = strings.TrimSpace(idStr)
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf("invalid admin ID: %s", idStr)
 		}
-		adminIDs = append(adminIDs, id)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func validateWebhook(cfg *Config) error {
+	if cfg.HTTPAddr == "" {
+		return fmt.Errorf("HTTP_ADDR is required in webhook mode")
+	}
+
+	u, err := url.Parse(cfg.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("WEBHOOK_URL must be an https:// URL")
 	}
 
-	return &Config{
-		TelegramToken:    token,
-		AdminGroupChatID: groupChatID,
-		AdminUserIDs:     adminIDs,
-	}, nil
-}
\ No newline at end of file
+	// Telegram принимает 1-256 символов A-Z, a-z, 0-9, _ и -
+	if len(cfg.WebhookSecret) == 0 || len(cfg.WebhookSecret) > 256 ||
+		strings.Trim(cfg.WebhookSecret, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+		return fmt.Errorf("WEBHOOK_SECRET must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+
+	if cfg.WebhookSelfSigned && cfg.TLSCertFile == "" {
+		return fmt.Errorf("WEBHOOK_SELF_SIGNED requires TLS_CERT_FILE")
+	}
+	return nil
+}
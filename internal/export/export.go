@@ -0,0 +1,97 @@
+// Package export выгружает заявки в CSV и XLSX
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// Форматы выгрузки
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var header = []string{"ID", "Имя", "Телефон", "Услуга", "Дата и время", "Статус", "Код статуса", "Врач", "Создана (UTC)"}
+
+func row(b *model.Booking) []string {
+	return []string{
+		strconv.Itoa(b.ID),
+		b.Name,
+		b.Phone,
+		b.Service,
+		b.DateTime,
+		model.StatusTitle(b.Status),
+		b.Status,
+		b.Doctor,
+		b.CreatedAt,
+	}
+}
+
+// FileName — имя файла выгрузки за период; пустая граница периода опускается
+func FileName(from, to, format string) string {
+	name := "bookings"
+	if from != "" {
+		name += "_from_" + from
+	}
+	if to != "" {
+		name += "_to_" + to
+	}
+	return name + "." + format
+}
+
+// Write выгружает заявки в указанном формате
+func Write(w io.Writer, format string, bookings []*model.Booking) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, bookings)
+	case FormatXLSX:
+		return WriteXLSX(w, bookings)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// plainPhone — телефон из цифр, пробелов, скобок и дефисов, например +7 999 123-45-67
+var plainPhone = regexp.MustCompile(`^\+?[0-9][0-9 ()-]*$`)
+
+// csvCell защищает значение от выполнения как формулы: Excel и другие табличные
+// редакторы считают формулой ячейку CSV, начинающуюся с =, +, - или @, а имя,
+// телефон и услугу вводит пациент. Апостроф в начале делает ячейку текстом, но
+// Excel его показывает, поэтому обычные телефоны остаются как есть: функций в них
+// нет. В XLSX ячейки строковые, и там это не нужно.
+func csvCell(s string) string {
+	if s == "" || !strings.ContainsRune("=+-@\t\r", rune(s[0])) || plainPhone.MatchString(s) {
+		return s
+	}
+	return "'" + s
+}
+
+// WriteCSV пишет CSV с BOM, чтобы Excel правильно открывал кириллицу
+func WriteCSV(w io.Writer, bookings []*model.Booking) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, b := range bookings {
+		cells := row(b)
+		for i := range cells {
+			cells[i] = csvCell(cells[i])
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,54 @@
+package export
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	var sb strings.Builder
+	err := WriteCSV(&sb, []*model.Booking{{
+		ID:       1,
+		Name:     `=HYPERLINK("http://evil.example","Иван")`,
+		Phone:    "+7 999 123-45-67",
+		Service:  "@SUM(A1:A2)",
+		DateTime: "2030-01-07 10:00",
+		Status:   model.StatusNew,
+		Doctor:   "-Петров",
+	}, {
+		ID:       2,
+		Name:     "Анна",
+		Phone:    "+cmd|' /C calc'!A0",
+		Service:  "Чистка",
+		DateTime: "2030-01-07 11:00",
+		Status:   model.StatusNew,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(sb.String(), "\ufeff"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := records[1]
+	want := map[int]string{
+		0: "1",
+		1: `'=HYPERLINK("http://evil.example","Иван")`,
+		2: "+7 999 123-45-67", // обычный телефон не портится апострофом
+		3: "'@SUM(A1:A2)",
+		4: "2030-01-07 10:00",
+		7: "'-Петров",
+	}
+	for i, w := range want {
+		if got[i] != w {
+			t.Errorf("column %s = %q, want %q", header[i], got[i], w)
+		}
+	}
+	if phone := records[2][2]; phone != "'+cmd|' /C calc'!A0" {
+		t.Errorf("formula in the phone column = %q", phone)
+	}
+}
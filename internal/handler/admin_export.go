@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/export"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// parseExportRange разбирает аргументы /admin_export [from] [to]; пустая граница не ограничивает период
+func parseExportRange(args string) (from, to string, err error) {
+	fields := strings.Fields(args)
+	if len(fields) > 2 {
+		return "", "", fmt.Errorf("слишком много аргументов")
+	}
+	for _, f := range fields {
+		if !isDate(f) {
+			return "", "", fmt.Errorf("некорректная дата %q", f)
+		}
+	}
+	if len(fields) > 0 {
+		from = fields[0]
+	}
+	if len(fields) > 1 {
+		to = fields[1]
+	}
+	if from != "" && to != "" && from > to {
+		return "", "", fmt.Errorf("начало периода позже конца")
+	}
+	return from, to, nil
+}
+
+func (h *CommandHandler) handleAdminExport(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermReports) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	from, to, err := parseExportRange(args)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()+". Используйте /admin_export [YYYY-MM-DD] [YYYY-MM-DD]"))
+		return
+	}
+
+	bookings, err := h.bookingService.ExportBookings(from, to)
+	if err != nil {
+		reportError(ctx, "admin_export", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения заявок."))
+		return
+	}
+
+	for _, format := range []string{export.FormatCSV, export.FormatXLSX} {
+		var buf bytes.Buffer
+		if err := export.Write(&buf, format, bookings); err != nil {
+			reportError(ctx, "admin_export_"+format, err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка формирования файла "+format+"."))
+			continue
+		}
+
+		doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: export.FileName(from, to, format), Bytes: buf.Bytes()})
+		if format == export.FormatCSV {
+			doc.Caption = fmt.Sprintf("Выгрузка заявок: %d шт.", len(bookings))
+		}
+		h.bot.Send(doc)
+	}
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+const (
+	listPageSize       = 10
+	listButtonsPerRow  = 5
+	listFilterPrefix   = "Фильтр: "
+	listFilterNone     = "все заявки"
+	listFilterHelpText = "Фильтры /admin_list:\n" +
+		"today, tomorrow, week — сегодня, завтра, ближайшие 7 дней\n" +
+		"2026-10-20 или 2026-10-20..2026-10-27 — дата или период\n" +
+		"status:new|confirmed|completed|cancelled|no_show\n" +
+		"service:<часть названия услуги>\n" +
+		"doctor:<часть имени врача>\n\n" +
+		"Пример: /admin_list today status:new"
+)
+
+// parseListFilter разбирает аргументы /admin_list. Значения service: и doctor:
+// могут состоять из нескольких слов — все слова до следующего фильтра.
+func parseListFilter(args string, now time.Time) (model.BookingFilter, error) {
+	var f model.BookingFilter
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	lastKey := ""
+
+	for _, token := range strings.Fields(args) {
+		key, value, hasKey := strings.Cut(token, ":")
+		if hasKey {
+			key = strings.ToLower(key)
+		}
+
+		switch {
+		case token == "today" || token == "сегодня":
+			f.DateFrom, f.DateTo = today.Format("2006-01-02"), today.Format("2006-01-02")
+		case token == "tomorrow" || token == "завтра":
+			tomorrow := today.AddDate(0, 0, 1).Format("2006-01-02")
+			f.DateFrom, f.DateTo = tomorrow, tomorrow
+		case token == "week" || token == "неделя":
+			f.DateFrom, f.DateTo = today.Format("2006-01-02"), today.AddDate(0, 0, 6).Format("2006-01-02")
+		case isDate(token):
+			f.DateFrom, f.DateTo = token, token
+		case strings.Contains(token, ".."):
+			from, to, _ := strings.Cut(token, "..")
+			if !isDate(from) || !isDate(to) || from > to {
+				return f, fmt.Errorf("некорректный период %q", token)
+			}
+			f.DateFrom, f.DateTo = from, to
+		case hasKey && key == "status":
+			if !model.IsValidStatus(value) {
+				return f, fmt.Errorf("неизвестный статус %q", value)
+			}
+			f.Status = value
+		case hasKey && key == "service":
+			f.Service = value
+		case hasKey && key == "doctor":
+			f.Doctor = value
+		case lastKey == "service":
+			f.Service = strings.TrimSpace(f.Service + " " + token)
+			continue
+		case lastKey == "doctor":
+			f.Doctor = strings.TrimSpace(f.Doctor + " " + token)
+			continue
+		default:
+			return f, fmt.Errorf("неизвестный фильтр %q", token)
+		}
+
+		lastKey = ""
+		if hasKey {
+			lastKey = key
+		}
+	}
+
+	return f, nil
+}
+
+// formatListFilter записывает фильтр для заголовка списка в виде, который
+// понимает parseListFilter. Даты всегда абсолютные: по ним видно, за какой
+// период показан старый список.
+func formatListFilter(f model.BookingFilter) string {
+	var parts []string
+	switch {
+	case f.DateFrom != "" && f.DateFrom == f.DateTo:
+		parts = append(parts, f.DateFrom)
+	case f.DateFrom != "" || f.DateTo != "":
+		from, to := f.DateFrom, f.DateTo
+		if from == "" {
+			from = "0000-01-01"
+		}
+		if to == "" {
+			to = "9999-12-31"
+		}
+		parts = append(parts, from+".."+to)
+	}
+	if f.Status != "" {
+		parts = append(parts, "status:"+f.Status)
+	}
+	if f.Service != "" {
+		parts = append(parts, "service:"+f.Service)
+	}
+	if f.Doctor != "" {
+		parts = append(parts, "doctor:"+f.Doctor)
+	}
+
+	if len(parts) == 0 {
+		return listFilterNone
+	}
+	return strings.Join(parts, " ")
+}
+
+// renderBookingList готовит текст и клавиатуру страницы списка заявок
+func renderBookingList(s *service.BookingService, f model.BookingFilter, page int) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	bookings, total, err := s.ListBookings(f, page, listPageSize)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	pages := (total + listPageSize - 1) / listPageSize
+	if total > 0 && page >= pages {
+		// Список мог сократиться после удаления — показываем последнюю страницу
+		page = pages - 1
+		bookings, total, err = s.ListBookings(f, page, listPageSize)
+		if err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, err
+		}
+	}
+
+	var sb strings.Builder
+	if total == 0 {
+		sb.WriteString("📋 Заявок не найдено.\n")
+	} else {
+		first := page*listPageSize + 1
+		fmt.Fprintf(&sb, "📋 Заявки %d–%d из %d (стр. %d/%d)\n", first, first+len(bookings)-1, total, page+1, pages)
+	}
+	sb.WriteString(listFilterPrefix + formatListFilter(f) + "\n")
+
+	for _, b := range bookings {
+		sb.WriteString("\n" + formatBookingLine(b) + "\n")
+	}
+	rows := bookingCardButtons(bookings)
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️ Назад", "list:"+strconv.Itoa(page-1)))
+	}
+	nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("🔄", "list:"+strconv.Itoa(page)))
+	if page+1 < pages {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Вперёд ▶️", "list:"+strconv.Itoa(page+1)))
+	}
+	rows = append(rows, nav)
+
+	return sb.String(), tgbotapi.NewInlineKeyboardMarkup(rows...), nil
+}
+
+// bookingCardButtons — кнопки "#ID", открывающие карточки заявок
+func bookingCardButtons(bookings []*model.Booking) [][]tgbotapi.InlineKeyboardButton {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, b := range bookings {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("#"+strconv.Itoa(b.ID), "card:"+strconv.Itoa(b.ID)))
+		if len(row) == listButtonsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// formatBookingLine — краткое описание заявки для списков
+func formatBookingLine(b *model.Booking) string {
+	line := "#" + strconv.Itoa(b.ID) + " · " + b.DateTime + " · " + model.StatusTitle(b.Status) + "\n" +
+		b.Name + ", " + b.Phone + ", " + b.Service
+	if b.Doctor != "" {
+		line += ", врач: " + b.Doctor
+	}
+	return line
+}
+
+// formatBookingCard — полное описание заявки
+func formatBookingCard(b *model.Booking) string {
+	text := "ID: " + strconv.Itoa(b.ID) + "\n" +
+		"Имя: " + b.Name + "\n" +
+		"Телефон: " + b.Phone + "\n" +
+		"Услуга: " + b.Service + "\n" +
+		"Дата и время: " + b.DateTime + "\n" +
+		"Статус: " + model.StatusTitle(b.Status)
+	if b.Doctor != "" {
+		text += "\nВрач: " + b.Doctor
+	}
+	if b.VisitNote != "" {
+		text += "\nЗаметка врача: " + b.VisitNote
+	}
+	return text
+}
+
+// bookingCardKeyboard — действия администратора над заявкой
+func bookingCardKeyboard(b *model.Booking) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(b.ID)
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", "edit:"+id),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить заявку", "delete:"+id),
+		),
+	)
+}
+
+// editBack возвращает карточке исходные кнопки вместо выбора поля
+const editBack = "back"
+
+// editFieldsKeyboard — выбор поля заявки для изменения
+func editFieldsKeyboard(id int) tgbotapi.InlineKeyboardMarkup {
+	prefix := "edit:" + strconv.Itoa(id) + ":"
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Имя", prefix+model.EditName),
+			tgbotapi.NewInlineKeyboardButtonData("Телефон", prefix+model.EditPhone),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Услуга", prefix+model.EditService),
+			tgbotapi.NewInlineKeyboardButtonData("Дата и время", prefix+model.EditTime),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Врач", prefix+model.EditDoctor),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Назад", prefix+editBack),
+		),
+	)
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/chart"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+const (
+	// statsTopItems ограничивает разбивки по услугам и врачам
+	statsTopItems = 5
+	// chartServices — сколько услуг показать на графике, остальные объединяются
+	chartServices = 8
+)
+
+var weekdayShort = [...]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"}
+
+// statsPeriod — период статистики и предыдущий период той же длины для сравнения
+type statsPeriod struct {
+	Title          string
+	From, To       string
+	PrevFrom       string
+	PrevTo         string
+	PrevTitleShort string
+}
+
+// parseStatsPeriod разбирает аргумент /admin_stats: week, month (по умолчанию) или YYYY-MM
+func parseStatsPeriod(arg string, now time.Time) (statsPeriod, error) {
+	const layout = "2006-01-02"
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch arg = strings.TrimSpace(arg); arg {
+	case "week", "неделя":
+		from := today.AddDate(0, 0, -6)
+		return statsPeriod{
+			Title:          "последние 7 дней",
+			From:           from.Format(layout),
+			To:             today.Format(layout),
+			PrevFrom:       from.AddDate(0, 0, -7).Format(layout),
+			PrevTo:         from.AddDate(0, 0, -1).Format(layout),
+			PrevTitleShort: "пред. 7 дней",
+		}, nil
+	case "", "month", "месяц":
+		return monthPeriod(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())), nil
+	default:
+		month, err := time.ParseInLocation("2006-01", arg, now.Location())
+		if err != nil {
+			return statsPeriod{}, fmt.Errorf("неизвестный период %q", arg)
+		}
+		return monthPeriod(month), nil
+	}
+}
+
+func monthPeriod(first time.Time) statsPeriod {
+	const layout = "2006-01-02"
+	prev := first.AddDate(0, -1, 0)
+	return statsPeriod{
+		Title:          first.Format("2006-01"),
+		From:           first.Format(layout),
+		To:             first.AddDate(0, 1, -1).Format(layout),
+		PrevFrom:       prev.Format(layout),
+		PrevTo:         first.AddDate(0, 0, -1).Format(layout),
+		PrevTitleShort: prev.Format("2006-01"),
+	}
+}
+
+// formatPeriodStats описывает статистику периода и сравнение с предыдущим
+func formatPeriodStats(p statsPeriod, cur, prev *model.BookingStats) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "📅 Приёмы за %s (%s — %s):\n", p.Title, p.From, p.To)
+	fmt.Fprintf(&sb, "Всего: %d (%s: %d, %s)\n", cur.Total, p.PrevTitleShort, prev.Total, formatChange(cur.Total, prev.Total))
+	fmt.Fprintf(&sb, "Отмены: %d (%s, было %s)\n", cur.Cancelled, formatPercent(cur.CancellationRate()), formatPercent(prev.CancellationRate()))
+	fmt.Fprintf(&sb, "Неявки: %d (%s, было %s)\n", cur.NoShow, formatPercent(cur.NoShowRate()), formatPercent(prev.NoShowRate()))
+	fmt.Fprintf(&sb, "Завершено: %d\n", cur.Completed)
+
+	if cur.Total == 0 {
+		return sb.String()
+	}
+
+	if cur.LeadTimeAvg > 0 {
+		fmt.Fprintf(&sb, "Запись заранее: в среднем %s, медиана %s\n", formatLeadTime(cur.LeadTimeAvg), formatLeadTime(cur.LeadTimeMedian))
+	}
+
+	sb.WriteString("\n🦷 Услуги:\n")
+	writeItems(&sb, cur.ByService, "Другое")
+
+	sb.WriteString("\n👨‍⚕️ Врачи:\n")
+	writeItems(&sb, cur.ByDoctor, "Не назначен")
+
+	sb.WriteString("\n📆 По дням недели:\n")
+	// Неделя с понедельника
+	var days []string
+	for i := 1; i <= 7; i++ {
+		wd := i % 7
+		days = append(days, fmt.Sprintf("%s %d", weekdayShort[wd], cur.ByWeekday[wd]))
+	}
+	sb.WriteString(strings.Join(days, " · ") + "\n")
+
+	sb.WriteString("\n🕘 По часам:\n")
+	var hours []string
+	for hour, count := range cur.ByHour {
+		if count > 0 {
+			hours = append(hours, fmt.Sprintf("%02d:00 — %d", hour, count))
+		}
+	}
+	sb.WriteString(strings.Join(hours, "\n") + "\n")
+
+	return sb.String()
+}
+
+func writeItems(sb *strings.Builder, items []model.StatItem, emptyName string) {
+	for i, item := range items {
+		if i == statsTopItems {
+			fmt.Fprintf(sb, "… и ещё %d\n", len(items)-statsTopItems)
+			break
+		}
+		name := item.Name
+		if name == "" {
+			name = emptyName
+		}
+		fmt.Fprintf(sb, "%s — %d\n", name, item.Count)
+	}
+}
+
+func formatPercent(rate float64) string {
+	return fmt.Sprintf("%.0f%%", rate*100)
+}
+
+// formatChange показывает изменение относительно предыдущего периода
+func formatChange(cur, prev int) string {
+	if prev == 0 {
+		if cur == 0 {
+			return "без изменений"
+		}
+		return "рост с нуля"
+	}
+	change := (float64(cur) - float64(prev)) / float64(prev) * 100
+	if math.Abs(change) < 0.5 {
+		return "без изменений"
+	}
+	return fmt.Sprintf("%+.0f%%", change)
+}
+
+func formatLeadTime(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%.0f ч", d.Hours())
+	}
+	return fmt.Sprintf("%.1f дн", d.Hours()/24)
+}
+
+// handleAdminChart отправляет графики за период: приёмы по дням, загрузка по дням недели и часам, услуги
+func (h *CommandHandler) handleAdminChart(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermReports) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	period, err := parseStatsPeriod(args, h.now())
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()+". Используйте /admin_chart week, /admin_chart month или /admin_chart 2026-10"))
+		return
+	}
+
+	stats, err := h.bookingService.GetPeriodStats(period.From, period.To)
+	if err != nil {
+		reportError(ctx, "admin_chart", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения статистики."))
+		return
+	}
+
+	charts, err := renderStatsCharts(period, stats)
+	if err != nil {
+		reportError(ctx, "admin_chart_render", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка построения графиков."))
+		return
+	}
+
+	for i, png := range charts {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileBytes{Name: fmt.Sprintf("chart-%d.png", i+1), Bytes: png})
+		h.bot.Send(photo)
+	}
+}
+
+func renderStatsCharts(p statsPeriod, stats *model.BookingStats) ([][]byte, error) {
+	// По дням
+	var dayLabels []string
+	var dayValues []int
+	for _, item := range stats.ByDate {
+		dayLabels = append(dayLabels, item.Name[8:10]+"."+item.Name[5:7])
+		dayValues = append(dayValues, item.Count)
+	}
+	perDay, err := chart.Bars("Приёмы по дням, "+p.Title, dayLabels, dayValues)
+	if err != nil {
+		return nil, err
+	}
+
+	// Загрузка: часы от начала рабочего дня до последнего занятого часа
+	firstHour, lastHour := 9, 18
+	for wd := range stats.Load {
+		for hour, count := range stats.Load[wd] {
+			if count > 0 {
+				firstHour, lastHour = min(firstHour, hour), max(lastHour, hour)
+			}
+		}
+	}
+	var hourLabels []string
+	for hour := firstHour; hour <= lastHour; hour++ {
+		hourLabels = append(hourLabels, fmt.Sprintf("%02d", hour))
+	}
+	var dayNames []string
+	var load [][]int
+	for i := 1; i <= 7; i++ {
+		wd := i % 7
+		dayNames = append(dayNames, weekdayShort[wd])
+		load = append(load, stats.Load[wd][firstHour:lastHour+1])
+	}
+	heatmap, err := chart.Heatmap("Загрузка по дням недели и часам, "+p.Title, dayNames, hourLabels, load)
+	if err != nil {
+		return nil, err
+	}
+
+	// Услуги
+	var serviceLabels []string
+	var serviceValues []int
+	for i, item := range stats.ByService {
+		if i == chartServices {
+			serviceLabels = append(serviceLabels, "Остальные")
+			serviceValues = append(serviceValues, 0)
+		}
+		if i >= chartServices {
+			serviceValues[chartServices] += item.Count
+			continue
+		}
+		name := item.Name
+		if name == "" {
+			name = "Другое"
+		}
+		serviceLabels = append(serviceLabels, name)
+		serviceValues = append(serviceValues, item.Count)
+	}
+	services, err := chart.HorizontalBars("Услуги, "+p.Title, serviceLabels, serviceValues)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{perDay, heatmap, services}, nil
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+const (
+	// messageLimit — предел длины сообщения Telegram с запасом под разметку
+	messageLimit       = 4000
+	agendaNameWidth    = 16
+	agendaServiceWidth = 14
+)
+
+var weekdayNames = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+
+// SendAgenda отправляет расписание на день: компактная таблица по каждому врачу.
+// Используется командами /admin_today, /admin_tomorrow и ежедневной рассылкой.
+func (h *CommandHandler) SendAgenda(ctx context.Context, chatID int64, day time.Time) error {
+	date := day.Format("2006-01-02")
+	bookings, err := h.bookingService.GetDayBookings(date)
+	if err != nil {
+		return err
+	}
+
+	header := "🗓 Расписание на " + date + " (" + weekdayNames[day.Weekday()] + ")"
+	if len(bookings) == 0 {
+		_, err = h.bot.Send(tgbotapi.NewMessage(chatID, header+"\n\nЗаписей нет."))
+		return err
+	}
+
+	var parts []string
+	for _, group := range groupByDoctor(bookings) {
+		parts = append(parts, formatAgendaGroup(group))
+	}
+
+	// Если всё не помещается в одно сообщение, отправляем врачей по отдельности
+	messages := []string{header + "\n\n" + strings.Join(parts, "\n\n")}
+	if len(messages[0]) > messageLimit {
+		messages = append([]string{header}, parts...)
+	}
+
+	for _, text := range messages {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = tgbotapi.ModeHTML
+		if _, err := h.bot.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByDoctor разбивает отсортированные по врачу заявки на группы
+func groupByDoctor(bookings []*model.Booking) [][]*model.Booking {
+	var groups [][]*model.Booking
+	for i, b := range bookings {
+		if i == 0 || b.Doctor != bookings[i-1].Doctor {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], b)
+	}
+	return groups
+}
+
+func formatAgendaGroup(group []*model.Booking) string {
+	doctor := group[0].Doctor
+	if doctor == "" {
+		doctor = "Врач не назначен"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("👨‍⚕️ <b>" + html.EscapeString(doctor) + "</b> — " + pluralBookings(len(group)) + "\n<pre>")
+	for _, b := range group {
+		sb.WriteString(timeOf(b.DateTime) + " " +
+			padRight(b.Name, agendaNameWidth) + " " +
+			padRight(b.Service, agendaServiceWidth) + " " +
+			model.StatusTitle(b.Status) + "\n")
+	}
+	sb.WriteString("</pre>")
+	return sb.String()
+}
+
+// timeOf выделяет HH:MM из "YYYY-MM-DD HH:MM"
+func timeOf(datetime string) string {
+	if len(datetime) < 16 {
+		return "--:--"
+	}
+	return datetime[11:16]
+}
+
+// padRight обрезает или дополняет строку пробелами до ширины в символах и экранирует её для HTML
+func padRight(s string, width int) string {
+	if utf8.RuneCountInString(s) > width {
+		s = string([]rune(s)[:width-1]) + "…"
+	}
+	return html.EscapeString(s + strings.Repeat(" ", width-utf8.RuneCountInString(s)))
+}
+
+func pluralBookings(n int) string {
+	word := "записей"
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		word = "запись"
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		word = "записи"
+	}
+	return strconv.Itoa(n) + " " + word
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Действия кнопок уведомления о заявке: "booking:ID:действие"
+const (
+	notifyConfirm = "confirm"
+	notifyCancel  = "cancel"
+	notifyCall    = "call"
+)
+
+// newBookingNotification готовит уведомление администраторам о новой заявке.
+// Его отправляет диспетчер outbox, а не обработчик.
+func newBookingNotification(chatID int64, booking *model.Booking, adminName string) *model.Notification {
+	title := "Новая запись на приём:"
+	if booking.AdminID != 0 {
+		title = "Новая запись на приём (оформил администратор " + adminName + "):"
+	}
+	markup, _ := json.Marshal(notificationKeyboard(booking))
+	return &model.Notification{
+		ChatID:      chatID,
+		Text:        formatNotification(title, booking, ""),
+		ReplyMarkup: string(markup),
+	}
+}
+
+// formatNotification — текст уведомления: заголовок, заявка и отметка о том, кто
+// её обработал
+func formatNotification(title string, b *model.Booking, handled string) string {
+	text := title + "\n\n" +
+		"Имя: " + b.Name + "\n" +
+		"Телефон: " + b.Phone + "\n" +
+		"Услуга: " + b.Service + "\n" +
+		"Дата и время: " + b.DateTime
+	if b.Doctor != "" {
+		text += "\nВрач: " + b.Doctor
+	}
+	text += "\nСтатус: " + model.StatusTitle(b.Status)
+	if handled != "" {
+		text += "\n\n" + handled
+	}
+	return text
+}
+
+// notificationKeyboard — действия над заявкой прямо из уведомления; набор зависит
+// от статуса. Telegram не принимает tel: в кнопках, поэтому «Позвонить» присылает
+// номер сообщением, где он открывается набором.
+func notificationKeyboard(b *model.Booking) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.Itoa(b.ID)
+	var actions []tgbotapi.InlineKeyboardButton
+	if b.Status == model.StatusNew {
+		actions = append(actions, tgbotapi.NewInlineKeyboardButtonData("✅ Подтвердить", "booking:"+id+":"+notifyConfirm))
+	}
+	if b.Status == model.StatusNew || b.Status == model.StatusConfirmed {
+		actions = append(actions,
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Перенести", "edit:"+id+":"+model.EditTime),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отменить", "booking:"+id+":"+notifyCancel),
+		)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	if len(actions) > 0 {
+		rows = append(rows, actions)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("📞 Позвонить", "booking:"+id+":"+notifyCall),
+		tgbotapi.NewInlineKeyboardButtonData("📋 Карточка", "card:"+id),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleNotificationCallback обрабатывает кнопки уведомления о заявке
+func (h *CallbackHandler) handleNotificationCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(data, "booking:"), ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректный ID заявки."))
+		return
+	}
+
+	perm := model.PermManageBookings
+	if action == notifyCall {
+		perm = model.PermViewBookings
+	}
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, perm) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	var status, done, handled string
+	switch action {
+	case notifyCall:
+		h.sendPhone(ctx, callback, id)
+		return
+	case notifyConfirm:
+		status, done, handled = model.StatusConfirmed, "Заявка подтверждена.", "✅ Подтвердил(а) "
+	case notifyCancel:
+		status, done, handled = model.StatusCancelled, "Заявка отменена.", "❌ Отменил(а) "
+	default:
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Неизвестное действие."))
+		return
+	}
+
+	booking, err := h.bookingService.SetStatus(id, status)
+	if err != nil {
+		text := "Ошибка изменения заявки."
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			text = "Заявка с таким ID не найдена."
+		case errors.Is(err, service.ErrBookingClosed):
+			text = "Заявка уже закрыта."
+		default:
+			reportError(ctx, "notification_"+action, err)
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, done))
+	h.updateNotification(ctx, booking, handled+callback.From.FirstName)
+}
+
+// sendPhone присылает телефон пациента сообщением, чтобы позвонить в одно касание
+func (h *CallbackHandler) sendPhone(ctx context.Context, callback *tgbotapi.CallbackQuery, id int) {
+	booking, err := h.bookingService.GetBookingByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка с таким ID не найдена."))
+		} else {
+			reportError(ctx, "notification_call", err)
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения заявки."))
+		}
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	h.bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "📞 "+booking.Name+": "+booking.Phone))
+}
+
+// updateNotification переписывает отправленное уведомление о заявке: текущие
+// данные, статус, кто обработал, и подходящие статусу кнопки
+func (h *CallbackHandler) updateNotification(ctx context.Context, booking *model.Booking, handled string) {
+	n, err := h.bookingService.GetNotification(booking.ID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			reportError(ctx, "booking_notification", err)
+		}
+		return
+	}
+
+	title, _, _ := strings.Cut(n.Text, "\n\n")
+	edit := tgbotapi.NewEditMessageTextAndMarkup(n.ChatID, n.MessageID,
+		formatNotification(title, booking, handled), notificationKeyboard(booking))
+	h.bot.Request(edit)
+}
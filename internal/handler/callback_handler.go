@@ -1,92 +1,239 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/REmakerzz/dental-clinic-bot/internal/calendar"
 	"github.com/REmakerzz/dental-clinic-bot/internal/config"
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
 	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
 	"github.com/REmakerzz/dental-clinic-bot/internal/service"
 	"github.com/REmakerzz/dental-clinic-bot/internal/ui"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type CallbackHandler struct {
-	bot            *tgbotapi.BotAPI
-	bookingService *service.BookingService
-	config         *config.Config
-	userBookings   map[int64]*model.Booking
+	bot             Sender
+	bookingService  *service.BookingService
+	doctorService   *service.DoctorService
+	patientService  *service.PatientService
+	waitlistService *service.WaitlistService
+	access          *service.AccessService
+	config          *config.Config
+	userBookings    Sessions
+	now             func() time.Time
 }
 
-func NewCallbackHandler(bot *tgbotapi.BotAPI, bookingService *service.BookingService, config *config.Config, userBookings map[int64]*model.Booking) *CallbackHandler {
+func NewCallbackHandler(bot Sender, bookingService *service.BookingService, doctorService *service.DoctorService, patientService *service.PatientService, waitlistService *service.WaitlistService, access *service.AccessService, config *config.Config, userBookings Sessions) *CallbackHandler {
 	return &CallbackHandler{
-		bot:            bot,
-		bookingService: bookingService,
-		config:         config,
-		userBookings:   userBookings,
+		bot:             bot,
+		bookingService:  bookingService,
+		doctorService:   doctorService,
+		patientService:  patientService,
+		waitlistService: waitlistService,
+		access:          access,
+		config:          config,
+		userBookings:    userBookings,
+		now:             time.Now,
 	}
 }
 
-func (h *CallbackHandler) HandleCallback(callback *tgbotapi.CallbackQuery) {
+func (h *CallbackHandler) HandleCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	data := callback.Data
+	logging.FromContext(ctx).Debug("callback received", "data", data)
 
 	if strings.HasPrefix(data, "delete:") {
-		h.handleDeleteCallback(callback, data)
+		h.handleDeleteCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "list:") {
+		h.handleListPage(ctx, callback, data)
+	} else if strings.HasPrefix(data, "card:") {
+		h.handleBookingCard(ctx, callback, data)
+	} else if strings.HasPrefix(data, "edit:") {
+		h.handleEditCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "doctor:") {
+		h.handleDoctorCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "wait:") {
+		h.handleWaitlistCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "offer:") {
+		h.handleOfferCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "patient:") {
+		h.handlePatientCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "booking:") {
+		h.handleNotificationCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "visit:") {
+		h.handleVisitCallback(ctx, callback, data)
+	} else if strings.HasPrefix(data, "date:") {
+		h.handleDateCallback(ctx, callback, data)
 	} else if strings.HasPrefix(data, "time:") {
-		h.handleTimeSelection(callback, data)
+		h.handleTimeSelection(ctx, callback, data)
 	} else {
 		callbackResp := tgbotapi.NewCallback(callback.ID, "Неизвестный callback.")
 		h.bot.Request(callbackResp)
 	}
 }
 
-func (h *CallbackHandler) handleTimeSelection(callback *tgbotapi.CallbackQuery, data string) {
+func (h *CallbackHandler) handleTimeSelection(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
 	chatID := callback.Message.Chat.ID
 	datetime := strings.TrimPrefix(data, "time:")
 
 	// Get the booking from the map
-	booking, exists := h.userBookings[chatID]
+	key := callbackKey(callback)
+	booking, exists := h.userBookings.lookup(h.access, key)
 	if !exists {
 		callbackResp := tgbotapi.NewCallback(callback.ID, "Ошибка: сессия бронирования не найдена.")
 		h.bot.Request(callbackResp)
 		return
 	}
 
+	if booking.EditField == model.EditTime {
+		h.handleReschedule(ctx, callback, booking, datetime)
+		return
+	}
+
 	// Set the datetime
 	booking.DateTime = datetime
 
-	// Save the booking
-	err := h.bookingService.SaveBooking(booking)
+	// Save the booking together with the notification for admins
+	err := h.bookingService.SaveBooking(booking, h.now(), func(b *model.Booking) *model.Notification {
+		return newBookingNotification(h.config.AdminGroupChatID, b, callback.From.FirstName)
+	})
 	if err != nil {
-		callbackResp := tgbotapi.NewCallback(callback.ID, "Ошибка при сохранении записи.")
+		text := "Ошибка при сохранении записи."
+		switch {
+		case errors.Is(err, repository.ErrSlotUnavailable):
+			text = "Это время уже занято, выберите другое."
+		case errors.Is(err, repository.ErrSlotPassed):
+			text = "Это время уже прошло, выберите другое."
+		default:
+			reportError(ctx, "save_booking", err)
+		}
+		callbackResp := tgbotapi.NewCallback(callback.ID, text)
 		h.bot.Request(callbackResp)
 		return
 	}
 
 	// Send confirmation to user
-	confirmMsg := tgbotapi.NewMessage(chatID, "Спасибо за запись! Заявка сохранена.")
-	confirmMsg.ReplyMarkup = ui.MainMenuKeyboard()
-	h.bot.Send(confirmMsg)
-
-	// Send notification to admin
-	adminMsg := tgbotapi.NewMessage(h.config.AdminGroupChatID, "Новая запись на приём:\n\n"+
-		"Имя: "+booking.Name+"\n"+
-		"Телефон: "+booking.Phone+"\n"+
-		"Услуга: "+booking.Service+"\n"+
-		"Дата и время: "+booking.DateTime)
-	h.bot.Send(adminMsg)
+	if booking.AdminID != 0 {
+		confirmMsg := tgbotapi.NewMessage(chatID, "✅ Пациент записан, заявка #"+strconv.Itoa(booking.ID)+".")
+		confirmMsg.ReplyMarkup = ui.AdminMenuKeyboard()
+		h.bot.Send(confirmMsg)
+	} else {
+		confirmMsg := tgbotapi.NewMessage(chatID, "Спасибо за запись! Заявка сохранена.")
+		confirmMsg.ReplyMarkup = ui.MainMenuKeyboard()
+		h.bot.Send(confirmMsg)
+
+		h.sendCalendarFile(ctx, chatID, booking)
+	}
 
 	// Delete the booking from the map
-	delete(h.userBookings, chatID)
+	delete(h.userBookings, key)
 
 	// Delete the time selection message
 	deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
 	h.bot.Request(deleteMsg)
 }
 
-func (h *CallbackHandler) handleDeleteCallback(callback *tgbotapi.CallbackQuery, data string) {
-	if !service.IsAdmin(callback.From.ID, h.config.AdminUserIDs) {
+// handleReschedule переносит существующую заявку на выбранное администратором время
+func (h *CallbackHandler) handleReschedule(ctx context.Context, callback *tgbotapi.CallbackQuery, session *model.Booking, datetime string) {
+	chatID := callback.Message.Chat.ID
+
+	booking, err := h.bookingService.GetBookingByID(session.ID)
+	if err != nil {
+		delete(h.userBookings, callbackKey(callback))
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка не найдена."))
+		return
+	}
+
+	booking.DateTime = datetime
+	err = h.bookingService.UpdateBooking(booking, h.now())
+	if err != nil {
+		text := "Ошибка при изменении заявки."
+		switch {
+		case errors.Is(err, repository.ErrSlotUnavailable):
+			text = "Это время уже занято, выберите другое."
+		case errors.Is(err, repository.ErrSlotPassed):
+			text = "Это время уже прошло, выберите другое."
+		default:
+			reportError(ctx, "reschedule_booking", err)
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
+
+	delete(h.userBookings, callbackKey(callback))
+	h.updateNotification(ctx, booking, "🔁 Перенёс(ла) "+callback.From.FirstName)
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка перенесена."))
+	h.bot.Request(tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID))
+
+	msg := tgbotapi.NewMessage(chatID, "✅ Заявка #"+strconv.Itoa(booking.ID)+" перенесена.\n\n"+formatBookingCard(booking))
+	msg.ReplyMarkup = bookingCardKeyboard(booking)
+	h.bot.Send(msg)
+}
+
+// handleEditCallback обрабатывает "edit:ID" (показать поля) и "edit:ID:поле" (начать ввод)
+func (h *CallbackHandler) handleEditCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermManageBookings) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	idStr, field, hasField := strings.Cut(strings.TrimPrefix(data, "edit:"), ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректный ID заявки."))
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	if !hasField || field == editBack {
+		keyboard := editFieldsKeyboard(id)
+		if hasField {
+			keyboard = bookingCardKeyboard(&model.Booking{ID: id})
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		h.bot.Request(tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID, keyboard))
+		return
+	}
+
+	var prompt string
+	var keyboard interface{}
+	switch field {
+	case model.EditDoctor:
+		h.sendDoctorChoice(ctx, callback, id)
+		return
+	case model.EditName:
+		prompt = "Новое имя пациента:"
+	case model.EditPhone:
+		prompt = "Новый телефон пациента:"
+	case model.EditService:
+		prompt = "Новая услуга:"
+		keyboard = ui.ServiceKeyboard()
+	case model.EditTime:
+		prompt = "Новая дата приёма (формат: YYYY-MM-DD):"
+	default:
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Неизвестное поле."))
+		return
+	}
+
+	h.userBookings[callbackKey(callback)] = &model.Booking{ID: id, AdminID: callback.From.ID, EditField: field}
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+
+	msg := tgbotapi.NewMessage(chatID, "Заявка #"+idStr+". "+prompt)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	h.bot.Send(msg)
+}
+
+func (h *CallbackHandler) handleDeleteCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermManageBookings) {
 		callbackResp := tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции.")
 		h.bot.Request(callbackResp)
 		return
@@ -99,16 +246,21 @@ func (h *CallbackHandler) handleDeleteCallback(callback *tgbotapi.CallbackQuery,
 		return
 	}
 
-	err = h.bookingService.DeleteBookingByID(id)
+	// Заявка отменяется, а не удаляется, чтобы отмена попала в календари врачей
+	_, err = h.bookingService.SetStatus(id, model.StatusCancelled)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
 			h.bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Заявка с таким ID не найдена."))
-		} else {
-			h.bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Ошибка удаления заявки."))
+		case errors.Is(err, service.ErrBookingClosed):
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка уже закрыта."))
+		default:
+			reportError(ctx, "delete_booking", err)
+			h.bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Ошибка отмены заявки."))
 		}
 	} else {
 		// Уведомление Telegram, что все ок
-		callbackResp := tgbotapi.NewCallback(callback.ID, "Заявка успешно удалена.")
+		callbackResp := tgbotapi.NewCallback(callback.ID, "Заявка отменена.")
 		h.bot.Request(callbackResp)
 
 		// Удаление сообщения с заявкой
@@ -116,3 +268,85 @@ func (h *CallbackHandler) handleDeleteCallback(callback *tgbotapi.CallbackQuery,
 		h.bot.Request(deleteMsg)
 	}
 }
+
+// handleListPage перелистывает список заявок, редактируя то же сообщение
+func (h *CallbackHandler) handleListPage(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermViewBookings) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	page, err := strconv.Atoi(strings.TrimPrefix(data, "list:"))
+	if err != nil || page < 0 {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректная страница."))
+		return
+	}
+
+	filter, err := h.bookingService.ListFilter(callback.Message.Chat.ID, callback.Message.MessageID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			reportError(ctx, "admin_list_page", err)
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Список устарел, запросите /admin_list заново."))
+		return
+	}
+
+	text, keyboard, err := renderBookingList(h.bookingService, filter, page)
+	if err != nil {
+		reportError(ctx, "admin_list_page", err)
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения заявок."))
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	edit := tgbotapi.NewEditMessageTextAndMarkup(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+	h.bot.Request(edit)
+}
+
+// handleBookingCard показывает карточку заявки с кнопками действий
+func (h *CallbackHandler) handleBookingCard(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermViewBookings) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(data, "card:"))
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректный ID заявки."))
+		return
+	}
+
+	booking, err := h.bookingService.GetBookingByID(id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка с таким ID не найдена."))
+		} else {
+			reportError(ctx, "booking_card", err)
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения заявки."))
+		}
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, formatBookingCard(booking))
+	msg.ReplyMarkup = bookingCardKeyboard(booking)
+	h.bot.Send(msg)
+}
+
+// sendCalendarFile отправляет пациенту .ics, чтобы добавить приём в свой календарь
+func (h *CallbackHandler) sendCalendarFile(ctx context.Context, chatID int64, booking *model.Booking) {
+	var buf bytes.Buffer
+	err := calendar.WriteCalendar(&buf, "", []calendar.Event{{
+		Booking:     booking,
+		Summary:     "Стоматология: " + booking.Service,
+		Description: "Заявка #" + strconv.Itoa(booking.ID) + ". Если планы изменятся, сообщите клинике.",
+	}})
+	if err != nil {
+		reportError(ctx, "booking_ics", err)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "appointment.ics", Bytes: buf.Bytes()})
+	doc.Caption = "📅 Добавьте приём в свой календарь"
+	h.bot.Send(doc)
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -9,108 +11,219 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/REmakerzz/dental-clinic-bot/internal/config"
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
 	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
 	"github.com/REmakerzz/dental-clinic-bot/internal/service"
 	"github.com/REmakerzz/dental-clinic-bot/internal/ui"
 )
 
+// findResultLimit ограничивает выдачу /admin_find одним сообщением
+const findResultLimit = 20
+
 type CommandHandler struct {
-	bot            *tgbotapi.BotAPI
+	bot            Sender
 	groupChatID    int64
-	userBookings   map[int64]*model.Booking
+	userBookings   Sessions
 	bookingService *service.BookingService
+	doctorService  *service.DoctorService
+	patientService *service.PatientService
+	access         *service.AccessService
 	config         *config.Config
+	now            func() time.Time
 }
 
-func NewCommandHandler(bot *tgbotapi.BotAPI, groupChatID int64, bookingService *service.BookingService, userBookings map[int64]*model.Booking, cfg *config.Config) *CommandHandler {
+func NewCommandHandler(bot Sender, groupChatID int64, bookingService *service.BookingService, doctorService *service.DoctorService, patientService *service.PatientService, access *service.AccessService, userBookings Sessions, cfg *config.Config) *CommandHandler {
 	return &CommandHandler{
 		bot:            bot,
 		groupChatID:    groupChatID,
 		userBookings:   userBookings,
 		bookingService: bookingService,
+		doctorService:  doctorService,
+		patientService: patientService,
+		access:         access,
 		config:         cfg,
+		now:            time.Now,
 	}
 }
 
-func (h *CommandHandler) HandleMessage(msg *tgbotapi.Message) {
+func (h *CommandHandler) HandleMessage(ctx context.Context, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 
 	if msg.IsCommand() {
+		// В группе команда может быть адресована другому боту: /admin_list@other_bot
+		_, to, addressed := strings.Cut(msg.CommandWithAt(), "@")
+		if addressed && h.config.BotUserName != "" && !strings.EqualFold(to, h.config.BotUserName) {
+			return
+		}
+
+		logging.FromContext(ctx).Debug("command received", "command", msg.Command())
 		switch msg.Command() {
+		case "profile":
+			h.handleProfile(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
 		case "admin_help":
-			h.handleAdminHelp(chatID, msg.From.ID)
+			h.handleAdminHelp(ctx, chatID, msg.From.ID)
 
 		case "admin_list":
-			h.handleAdminList(chatID, msg.From.ID)
+			h.handleAdminList(ctx, chatID, msg.From.ID, msg.CommandArguments())
 
 		case "admin_stats":
-			h.handleAdminStats(chatID, msg.From.ID)
+			h.handleAdminStats(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_chart":
+			h.handleAdminChart(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_export":
+			h.handleAdminExport(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_today":
+			h.handleAdminAgenda(ctx, chatID, msg.From.ID, 0)
+
+		case "admin_tomorrow":
+			h.handleAdminAgenda(ctx, chatID, msg.From.ID, 1)
+
+		case "admin_new":
+			h.handleAdminNew(ctx, chatID, msg.From.ID)
+
+		case "admin_patient":
+			h.handleAdminPatient(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_find":
+			h.handleAdminFind(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_doctors":
+			h.handleAdminDoctors(ctx, chatID, msg.From.ID)
+
+		case "admin_doctor_add":
+			h.handleAdminDoctorAdd(ctx, chatID, msg.From.ID, msg.CommandArguments())
 
 		case "admin_delete":
-			h.handleAdminDelete(chatID, msg.From.ID, msg.CommandArguments())
+			h.handleAdminDelete(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_staff":
+			h.handleAdminStaff(ctx, chatID, msg.From.ID)
+
+		case "admin_grant":
+			h.handleAdminGrant(ctx, chatID, msg.From.ID, msg.CommandArguments())
+
+		case "admin_revoke":
+			h.handleAdminRevoke(ctx, chatID, msg.From.ID, msg.CommandArguments())
 
 		default:
-			h.bot.Send(tgbotapi.NewMessage(chatID, "Неизвестная команда."))
+			// Команду без @ в группе могли отправить другому боту
+			if msg.Chat.IsPrivate() || addressed {
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Неизвестная команда."))
+			}
 		}
 	} else {
 		// некомандные сообщения → можно потом сюда добавить обработку
-		h.handleBookingFlow(msg)
+		h.handleBookingFlow(ctx, msg)
 	}
 }
 
-func (h *CommandHandler) handleAdminHelp(chatID int64, userID int64) {
-	if service.IsAdmin(userID, h.config.AdminUserIDs) {
-		helpText := "Доступные админ-команды:\n\n" +
-			"/admin_list — Показать все заявки\n" +
-			"/admin_stats — Показать статистику\n" +
-			"/admin_delete N — Удалить заявку по ID\n" +
-			"/admin_help — Показать это сообщение\n"
+// adminCommands — справка по админ-командам и право, без которого команда недоступна
+var adminCommands = []struct {
+	permission string
+	help       string
+}{
+	{model.PermViewBookings, "/admin_list [фильтры] — Список заявок по страницам"},
+	{model.PermReports, "/admin_stats [week|month|YYYY-MM] — Показать статистику"},
+	{model.PermReports, "/admin_chart [week|month|YYYY-MM] — Графики статистики"},
+	{model.PermReports, "/admin_export [с] [по] — Выгрузить заявки в CSV и XLSX"},
+	{model.PermViewSchedule, "/admin_today — Расписание на сегодня"},
+	{model.PermViewSchedule, "/admin_tomorrow — Расписание на завтра"},
+	{model.PermManageBookings, "/admin_new — Записать пациента на приём"},
+	{model.PermViewBookings, "/admin_find текст — Найти заявки по имени, телефону или ID"},
+	{model.PermViewBookings, "/admin_patient ID|телефон — Карточка пациента с историей приёмов"},
+	{model.PermManageBookings, "/admin_delete N — Отменить заявку по ID"},
+	{model.PermManageDoctors, "/admin_doctors — Врачи и ссылки на их календари"},
+	{model.PermManageDoctors, "/admin_doctor_add Имя — Добавить врача"},
+	{model.PermManageStaff, "/admin_staff — Сотрудники и их роли"},
+	{model.PermManageStaff, "/admin_grant ID роль [имя] — Выдать роль сотруднику"},
+	{model.PermManageStaff, "/admin_revoke ID — Отозвать роль"},
+	{model.PermOwnSchedule, doctorDayButton + ", " + doctorWeekButton + " — Ваши приёмы (кнопки меню)"},
+}
 
-		h.bot.Send(tgbotapi.NewMessage(chatID, helpText))
-	} else {
+func (h *CommandHandler) handleAdminHelp(ctx context.Context, chatID int64, userID int64) {
+	role, err := h.access.Role(chatID, userID)
+	if err != nil {
+		reportError(ctx, "admin_help", err)
+	}
+	if role == "" {
 		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	// Показываем только команды, доступные роли
+	helpText := "Доступные админ-команды (ваша роль: " + model.RoleTitle(role) + "):\n\n"
+	for _, c := range adminCommands {
+		if model.HasPermission(role, c.permission) {
+			helpText += c.help + "\n"
+		}
 	}
+	helpText += "/admin_help — Показать это сообщение"
+	if model.HasPermission(role, model.PermViewBookings) {
+		helpText += "\n\n" + listFilterHelpText
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, helpText))
 }
 
-func (h *CommandHandler) handleAdminList(chatID int64, userID int64) {
-	if service.IsAdmin(userID, h.config.AdminUserIDs) {
-		bookings, err := h.bookingService.GetAllBookings()
+func (h *CommandHandler) handleAdminList(ctx context.Context, chatID int64, userID int64, args string) {
+	if h.access.Can(chatID, userID, model.PermViewBookings) {
+		filter, err := parseListFilter(args, h.now())
 		if err != nil {
-			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения заявок."))
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка в фильтре: "+err.Error()+"\n\n"+listFilterHelpText))
 			return
 		}
 
-		if len(bookings) == 0 {
-			h.bot.Send(tgbotapi.NewMessage(chatID, "Заявок пока нет."))
+		text, keyboard, err := renderBookingList(h.bookingService, filter, 0)
+		if err != nil {
+			reportError(ctx, "admin_list", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения заявок."))
 			return
 		}
 
-		for _, b := range bookings {
-			text := "ID: " + strconv.Itoa(b.ID) + "\n" +
-				"Имя: " + b.Name + "\n" +
-				"Телефон: " + b.Phone + "\n" +
-				"Услуга: " + b.Service + "\n" +
-				"Дата и время: " + b.DateTime
-
-			deleteButton := tgbotapi.NewInlineKeyboardButtonData("❌ Удалить заявку", "delete:"+strconv.Itoa(b.ID))
-			keyboard := tgbotapi.NewInlineKeyboardMarkup(
-				tgbotapi.NewInlineKeyboardRow(deleteButton),
-			)
-
-			msg := tgbotapi.NewMessage(chatID, text)
-			msg.ReplyMarkup = keyboard
-
-			h.bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = keyboard
+		sent, err := h.bot.Send(msg)
+		if err != nil {
+			return
+		}
+		// Фильтр запоминается по сообщению, чтобы листать список с тем же фильтром
+		if err := h.bookingService.SaveListFilter(chatID, sent.MessageID, filter); err != nil {
+			reportError(ctx, "admin_list", err)
 		}
 	} else {
 		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
 	}
 }
 
-func (h *CommandHandler) handleAdminStats(chatID int64, userID int64) {
-	if service.IsAdmin(userID, h.config.AdminUserIDs) {
+func (h *CommandHandler) handleAdminStats(ctx context.Context, chatID int64, userID int64, args string) {
+	if h.access.Can(chatID, userID, model.PermReports) {
+		period, err := parseStatsPeriod(args, h.now())
+		if err != nil {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка: "+err.Error()+". Используйте /admin_stats week, /admin_stats month или /admin_stats 2026-10"))
+			return
+		}
+
 		total, today, last7Days, err := h.bookingService.GetBookingStats()
 		if err != nil {
+			reportError(ctx, "admin_stats", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения статистики."))
+			return
+		}
+
+		cur, err := h.bookingService.GetPeriodStats(period.From, period.To)
+		if err != nil {
+			reportError(ctx, "admin_stats", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения статистики."))
+			return
+		}
+		prev, err := h.bookingService.GetPeriodStats(period.PrevFrom, period.PrevTo)
+		if err != nil {
+			reportError(ctx, "admin_stats", err)
 			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения статистики."))
 			return
 		}
@@ -118,7 +231,8 @@ func (h *CommandHandler) handleAdminStats(chatID int64, userID int64) {
 		statsText := "📊 Статистика заявок:\n\n" +
 			"Всего заявок: " + strconv.Itoa(total) + "\n" +
 			"Заявок сегодня: " + strconv.Itoa(today) + "\n" +
-			"Заявок за последние 7 дней: " + strconv.Itoa(last7Days)
+			"Заявок за последние 7 дней: " + strconv.Itoa(last7Days) + "\n\n" +
+			formatPeriodStats(period, cur, prev)
 
 		h.bot.Send(tgbotapi.NewMessage(chatID, statsText))
 	} else {
@@ -126,100 +240,180 @@ func (h *CommandHandler) handleAdminStats(chatID int64, userID int64) {
 	}
 }
 
-func (h *CommandHandler) handleAdminDelete(chatID int64, userID int64, args string) {
-	if service.IsAdmin(userID, h.config.AdminUserIDs) {
+// handleAdminAgenda показывает расписание на день через offset дней от сегодня
+func (h *CommandHandler) handleAdminAgenda(ctx context.Context, chatID int64, userID int64, offset int) {
+	if h.access.Can(chatID, userID, model.PermViewSchedule) {
+		if err := h.SendAgenda(ctx, chatID, h.now().AddDate(0, 0, offset)); err != nil {
+			reportError(ctx, "admin_agenda", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения расписания."))
+		}
+	} else {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+	}
+}
+
+func (h *CommandHandler) handleAdminNew(ctx context.Context, chatID int64, userID int64) {
+	if h.access.Can(chatID, userID, model.PermManageBookings) {
+		h.userBookings[SessionKey{ChatID: chatID, UserID: userID}] = &model.Booking{Step: 1, AdminID: userID}
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Новая запись. Имя пациента:"))
+	} else {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+	}
+}
+
+func (h *CommandHandler) handleAdminFind(ctx context.Context, chatID int64, userID int64, args string) {
+	if h.access.Can(chatID, userID, model.PermViewBookings) {
+		query := strings.TrimSpace(args)
+		if query == "" {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Укажите, что искать: /admin_find Иванов, /admin_find 9991234 или /admin_find 123"))
+			return
+		}
+
+		bookings, err := h.bookingService.SearchBookings(query, findResultLimit)
+		if err != nil {
+			if errors.Is(err, repository.ErrSearchQueryTooShort) {
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Слишком короткий запрос: укажите хотя бы "+
+					strconv.Itoa(repository.MinSearchLength)+" символа имени или цифры телефона."))
+			} else {
+				reportError(ctx, "admin_find", err)
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка поиска заявок."))
+			}
+			return
+		}
+
+		if len(bookings) == 0 {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "По запросу «"+query+"» ничего не найдено."))
+			return
+		}
+
+		// Единственное совпадение сразу показываем карточкой с действиями
+		if len(bookings) == 1 {
+			msg := tgbotapi.NewMessage(chatID, formatBookingCard(bookings[0]))
+			msg.ReplyMarkup = bookingCardKeyboard(bookings[0])
+			h.bot.Send(msg)
+			return
+		}
+
+		text := "🔍 Найдено по запросу «" + query + "»: " + strconv.Itoa(len(bookings))
+		if len(bookings) == findResultLimit {
+			text += " (показаны первые " + strconv.Itoa(findResultLimit) + ", уточните запрос)"
+		}
+		text += "\n"
+		for _, b := range bookings {
+			text += "\n" + formatBookingLine(b) + "\n"
+		}
+
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(bookingCardButtons(bookings)...)
+		h.bot.Send(msg)
+	} else {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+	}
+}
+
+func (h *CommandHandler) handleAdminDelete(ctx context.Context, chatID int64, userID int64, args string) {
+	if h.access.Can(chatID, userID, model.PermManageBookings) {
 		id, err := strconv.Atoi(strings.TrimSpace(args))
 		if err != nil {
 			h.bot.Send(tgbotapi.NewMessage(chatID, "Пожалуйста, укажите корректный ID заявки: /admin_delete 123"))
 			return
 		}
 
-		err = h.bookingService.DeleteBookingByID(id)
+		// Заявка не удаляется, а отменяется: так отмена попадает в календари врачей
+		_, err = h.bookingService.SetStatus(id, model.StatusCancelled)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
 				h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка с таким ID не найдена."))
-			} else {
-				h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка удаления заявки."))
+			case errors.Is(err, service.ErrBookingClosed):
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка уже закрыта."))
+			default:
+				reportError(ctx, "admin_delete", err)
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка отмены заявки."))
 			}
 			return
 		}
 
-		h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка успешно удалена."))
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка отменена."))
 	} else {
 		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
 	}
 }
 
-func (h *CommandHandler) handleBookingFlow(msg *tgbotapi.Message) {
+func (h *CommandHandler) handleBookingFlow(ctx context.Context, msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
+	key := messageKey(msg)
 	text := msg.Text
 
+	// меню врача; незаконченная заметка отменяется
+	if text == doctorDayButton || text == doctorWeekButton {
+		delete(h.userBookings, key)
+		days := 1
+		if text == doctorWeekButton {
+			days = 7
+		}
+		h.handleDoctorSchedule(ctx, chatID, msg.From.ID, h.now(), days)
+		return
+	}
+
 	// стартуем процесс
 	if text == "🗓️ Записаться на приём" {
-		h.userBookings[chatID] = &model.Booking{Step: 1}
-		msg := tgbotapi.NewMessage(chatID, "Как вас зовут?")
-		h.bot.Send(msg)
+		h.startBooking(ctx, msg)
 		return
 	}
 
-	booking, exists := h.userBookings[chatID]
+	booking, exists := h.userBookings.lookup(h.access, key)
 	if exists {
+		if booking.EditField != "" {
+			h.handleBookingEdit(ctx, key, booking, text)
+			return
+		}
+
+		// Администратор оформляет запись за пациента — вопросы в третьем лице
+		byAdmin := booking.AdminID != 0
+
 		switch booking.Step {
 		case 1:
 			booking.Name = text
 			booking.Step++
-			msg := tgbotapi.NewMessage(chatID, "Пожалуйста, введите ваш номер телефона:")
-			h.bot.Send(msg)
+			prompt := "Пожалуйста, введите ваш номер телефона:"
+			if byAdmin {
+				prompt = "Телефон пациента:"
+			}
+			h.bot.Send(tgbotapi.NewMessage(chatID, prompt))
 		case 2:
 			booking.Phone = text
 			booking.Step++
-			msg := tgbotapi.NewMessage(chatID, "Какую услугу вы хотите получить?")
-			msg.ReplyMarkup = ui.ServiceKeyboard()
-			h.bot.Send(msg)
+			h.askService(chatID, byAdmin)
 		case 3:
 			booking.Service = text
 			booking.Step++
-			msg := tgbotapi.NewMessage(chatID, "На какую дату вы хотите записаться? (формат: YYYY-MM-DD)")
-			h.bot.Send(msg)
-		case 4:
-			// Validate date format
-			_, err := time.Parse("2006-01-02", text)
-			if err != nil {
-				msg := tgbotapi.NewMessage(chatID, "Неверный формат даты. Пожалуйста, используйте формат YYYY-MM-DD")
-				h.bot.Send(msg)
-				return
+			prompt := "На какую дату вы хотите записаться? (формат: YYYY-MM-DD)"
+			if byAdmin {
+				prompt = "Дата приёма (формат: YYYY-MM-DD):"
 			}
-
-			// Get available time slots for the selected date
-			slots, err := h.bookingService.GetAvailableTimeSlots(text)
-			if err != nil {
-				msg := tgbotapi.NewMessage(chatID, "Ошибка при получении доступного времени. Пожалуйста, попробуйте другую дату.")
-				h.bot.Send(msg)
-				return
-			}
-
-			if len(slots) == 0 {
-				msg := tgbotapi.NewMessage(chatID, "На выбранную дату нет доступного времени. Пожалуйста, выберите другую дату.")
-				h.bot.Send(msg)
-				return
-			}
-
-			// Create keyboard with available time slots
-			var keyboard [][]tgbotapi.InlineKeyboardButton
-			for _, slot := range slots {
-				timeStr := slot[11:16] // Extract time part (HH:MM)
-				keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
-					tgbotapi.NewInlineKeyboardButtonData(timeStr, "time:"+slot),
-				})
+			h.bot.Send(tgbotapi.NewMessage(chatID, prompt))
+		case 4:
+			if h.sendTimeSlots(ctx, chatID, text, booking) {
+				booking.Step++
 			}
-
-			msg := tgbotapi.NewMessage(chatID, "Выберите удобное время:")
-			msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-			h.bot.Send(msg)
-			booking.Step++
 		case 5:
 			// This step is handled by callback handler
 			return
+		case stepKnownPatient:
+			switch text {
+			case knownPatientYes:
+				booking.Step = 3
+				h.askService(chatID, byAdmin)
+			case knownPatientChange:
+				booking.Name, booking.Phone = "", ""
+				booking.Step = 1
+				msg := tgbotapi.NewMessage(chatID, "Как вас зовут?")
+				msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(false)
+				h.bot.Send(msg)
+			default:
+				h.askKnownPatient(chatID, booking)
+			}
 		default:
 			msg := tgbotapi.NewMessage(chatID, "Пожалуйста, выберите действие из меню.")
 			msg.ReplyMarkup = ui.MainMenuKeyboard()
@@ -230,7 +424,15 @@ func (h *CommandHandler) handleBookingFlow(msg *tgbotapi.Message) {
 	}
 
 	// если просто сообщение → показываем соответствующее меню
-	if service.IsAdmin(msg.From.ID, h.config.AdminUserIDs) || chatID == h.groupChatID {
+	role, err := h.access.Role(chatID, msg.From.ID)
+	if err != nil {
+		reportError(ctx, "staff_role", err)
+	}
+	if role == model.RoleDoctor && chatID != h.groupChatID {
+		msg := tgbotapi.NewMessage(chatID, "Доктор, пожалуйста выберите действие:")
+		msg.ReplyMarkup = ui.DoctorMenuKeyboard()
+		h.bot.Send(msg)
+	} else if role != "" || chatID == h.groupChatID {
 		msg := tgbotapi.NewMessage(chatID, "Администратор пожалуйста выберите действие:")
 		msg.ReplyMarkup = ui.AdminMenuKeyboard()
 		h.bot.Send(msg)
@@ -240,3 +442,101 @@ func (h *CommandHandler) handleBookingFlow(msg *tgbotapi.Message) {
 		h.bot.Send(msg)
 	}
 }
+
+// startBooking начинает запись. Пациенту, который уже записывался, предлагает
+// сохранённые имя и телефон вместо того, чтобы спрашивать их заново.
+func (h *CommandHandler) startBooking(ctx context.Context, msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+	booking := &model.Booking{Step: 1}
+	h.userBookings[messageKey(msg)] = booking
+
+	// Профиль привязан к Telegram ID, поэтому ведётся только в личном чате
+	if msg.Chat.IsPrivate() {
+		booking.PatientID = msg.From.ID
+		patient, err := h.patientService.GetPatient(msg.From.ID)
+		if err == nil {
+			booking.Name, booking.Phone = patient.Name, patient.Phone
+			booking.Step = stepKnownPatient
+			h.askKnownPatient(chatID, booking)
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			reportError(ctx, "get_patient", err)
+		}
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Как вас зовут?"))
+}
+
+func (h *CommandHandler) askKnownPatient(chatID int64, booking *model.Booking) {
+	msg := tgbotapi.NewMessage(chatID, "Записать вас как "+booking.Name+", "+booking.Phone+"?")
+	msg.ReplyMarkup = ui.KnownPatientKeyboard()
+	h.bot.Send(msg)
+}
+
+func (h *CommandHandler) askService(chatID int64, byAdmin bool) {
+	prompt := "Какую услугу вы хотите получить?"
+	if byAdmin {
+		prompt = "Услуга:"
+	}
+	msg := tgbotapi.NewMessage(chatID, prompt)
+	msg.ReplyMarkup = ui.ServiceKeyboard()
+	h.bot.Send(msg)
+}
+
+// sendTimeSlots предлагает свободное время на дату для сессии session. Возвращает
+// false, если выбрать нечего и нужно спросить дату заново.
+func (h *CommandHandler) sendTimeSlots(ctx context.Context, chatID int64, date string, session *model.Booking) bool {
+	msg, ok := timeSlotsMessage(ctx, h.bookingService, chatID, date, session, h.now())
+	h.bot.Send(msg)
+	return ok
+}
+
+// handleBookingEdit принимает новое значение поля, выбранного кнопкой "Изменить"
+func (h *CommandHandler) handleBookingEdit(ctx context.Context, key SessionKey, session *model.Booking, text string) {
+	chatID := key.ChatID
+	if session.EditField == model.EditNote {
+		h.saveVisitNote(ctx, key, session, text)
+		return
+	}
+	if session.EditField == model.EditMessage {
+		h.sendPatientMessage(ctx, key, session, text)
+		return
+	}
+	if session.EditField == model.EditTime {
+		// Дату спрашиваем текстом, время выбирается кнопкой и сохраняется в CallbackHandler
+		h.sendTimeSlots(ctx, chatID, text, session)
+		return
+	}
+
+	booking, err := h.bookingService.GetBookingByID(session.ID)
+	if err != nil {
+		delete(h.userBookings, key)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка #"+strconv.Itoa(session.ID)+" не найдена."))
+		return
+	}
+
+	switch session.EditField {
+	case model.EditName:
+		booking.Name = text
+	case model.EditPhone:
+		booking.Phone = text
+	case model.EditService:
+		booking.Service = text
+	}
+
+	delete(h.userBookings, key)
+	if err := h.bookingService.UpdateBooking(booking, h.now()); err != nil {
+		reportError(ctx, "edit_booking", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при изменении заявки."))
+		return
+	}
+
+	done := tgbotapi.NewMessage(chatID, "✅ Заявка #"+strconv.Itoa(booking.ID)+" изменена.")
+	done.ReplyMarkup = ui.AdminMenuKeyboard()
+	h.bot.Send(done)
+
+	card := tgbotapi.NewMessage(chatID, formatBookingCard(booking))
+	card.ReplyMarkup = bookingCardKeyboard(booking)
+	h.bot.Send(card)
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+const (
+	doctorDayButton  = "👨‍⚕️ Мой день"
+	doctorWeekButton = "📅 Моя неделя"
+
+	// visitNoteLimit — предел длины заметки врача в символах
+	visitNoteLimit     = 500
+	visitButtonsPerRow = 3
+	// visitButtonsLimit оставляет запас до ограничения Telegram в 100 кнопок
+	visitButtonsLimit = 90
+)
+
+// handleDoctorSchedule показывает врачу его приёмы на days дней начиная с from
+func (h *CommandHandler) handleDoctorSchedule(ctx context.Context, chatID int64, userID int64, from time.Time, days int) {
+	if !h.access.Can(chatID, userID, model.PermOwnSchedule) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	doctorID, ok := h.linkedDoctor(ctx, chatID, userID)
+	if !ok {
+		return
+	}
+
+	fromDate := from.Format("2006-01-02")
+	toDate := from.AddDate(0, 0, days-1).Format("2006-01-02")
+	bookings, err := h.doctorService.GetSchedule(doctorID, fromDate, toDate)
+	if err != nil {
+		reportError(ctx, "doctor_schedule", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения расписания."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatDoctorSchedule(from, days, bookings))
+	if len(bookings) > 0 {
+		msg.ReplyMarkup = visitButtons(bookings, days > 1)
+	}
+	h.bot.Send(msg)
+}
+
+// linkedDoctor возвращает врача, к которому привязан пользователь, или сообщает,
+// что привязки нет
+func (h *CommandHandler) linkedDoctor(ctx context.Context, chatID int64, userID int64) (int64, bool) {
+	doctorID, err := h.access.DoctorID(userID)
+	if err != nil {
+		reportError(ctx, "doctor_link", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения расписания."))
+		return 0, false
+	}
+	if doctorID == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ваша учётная запись не привязана к врачу. Обратитесь к владельцу бота."))
+		return 0, false
+	}
+	return doctorID, true
+}
+
+// saveVisitNote сохраняет заметку, начатую кнопкой "Заметка" под приёмом
+func (h *CommandHandler) saveVisitNote(ctx context.Context, key SessionKey, session *model.Booking, text string) {
+	chatID := key.ChatID
+	note := strings.TrimSpace(text)
+	if utf8.RuneCountInString(note) > visitNoteLimit {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заметка длиннее "+strconv.Itoa(visitNoteLimit)+" символов, сократите её."))
+		return
+	}
+	delete(h.userBookings, key)
+
+	doctorID, ok := h.linkedDoctor(ctx, chatID, session.AdminID)
+	if !ok {
+		return
+	}
+
+	booking, err := h.doctorService.SetVisitNote(doctorID, session.ID, note)
+	if err != nil {
+		text, known := visitErrorText(err)
+		if !known {
+			reportError(ctx, "visit_note", err)
+		}
+		h.bot.Send(tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "📝 Заметка сохранена.\n\n"+formatVisitCard(booking))
+	msg.ReplyMarkup = visitKeyboard(booking)
+	h.bot.Send(msg)
+}
+
+// handleVisitCallback обрабатывает кнопки приёма врача: "visit:ID" (карточка),
+// "visit:ID:completed", "visit:ID:no_show" (итог приёма) и "visit:ID:note" (заметка)
+func (h *CallbackHandler) handleVisitCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermOwnSchedule) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	doctorID, err := h.access.DoctorID(callback.From.ID)
+	if err != nil || doctorID == 0 {
+		if err != nil {
+			reportError(ctx, "doctor_link", err)
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ваша учётная запись не привязана к врачу."))
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(data, "visit:"), ":")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректный ID заявки."))
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	switch action {
+	case "":
+		booking, err := h.doctorService.GetVisit(doctorID, id)
+		if err != nil {
+			h.answerVisitError(ctx, callback, err)
+			return
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		msg := tgbotapi.NewMessage(chatID, formatVisitCard(booking))
+		msg.ReplyMarkup = visitKeyboard(booking)
+		h.bot.Send(msg)
+
+	case model.StatusCompleted, model.StatusNoShow:
+		booking, err := h.doctorService.MarkVisit(doctorID, id, action, h.now())
+		if err != nil {
+			h.answerVisitError(ctx, callback, err)
+			return
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Отмечено: "+model.StatusTitle(action)+"."))
+		h.bot.Request(tgbotapi.NewEditMessageTextAndMarkup(chatID, callback.Message.MessageID,
+			formatVisitCard(booking), visitKeyboard(booking)))
+
+	case model.EditNote:
+		if _, err := h.doctorService.GetVisit(doctorID, id); err != nil {
+			h.answerVisitError(ctx, callback, err)
+			return
+		}
+		h.userBookings[callbackKey(callback)] = &model.Booking{ID: id, AdminID: callback.From.ID, EditField: model.EditNote}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Заявка #"+idStr+". Заметка о приёме (до "+
+			strconv.Itoa(visitNoteLimit)+" символов):"))
+
+	default:
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Неизвестное действие."))
+	}
+}
+
+func (h *CallbackHandler) answerVisitError(ctx context.Context, callback *tgbotapi.CallbackQuery, err error) {
+	text, known := visitErrorText(err)
+	if !known {
+		reportError(ctx, "visit", err)
+	}
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, text))
+}
+
+// visitErrorText переводит ошибку действия врача в ответ; known — ошибка ожидаемая
+func visitErrorText(err error) (text string, known bool) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "Заявка не найдена.", true
+	case errors.Is(err, service.ErrOtherDoctor):
+		return "Это приём другого врача.", true
+	case errors.Is(err, service.ErrVisitNotStarted):
+		return "Приём ещё не начался.", true
+	case errors.Is(err, service.ErrVisitCancelled):
+		return "Приём отменён.", true
+	default:
+		return "Ошибка сохранения.", false
+	}
+}
+
+// formatDoctorSchedule — приёмы врача по дням
+func formatDoctorSchedule(from time.Time, days int, bookings []*model.Booking) string {
+	header := "🗓 Мои приёмы на " + from.Format("2006-01-02") + " (" + weekdayNames[from.Weekday()] + ")"
+	if days > 1 {
+		header = "🗓 Мои приёмы с " + from.Format("2006-01-02") + " по " + from.AddDate(0, 0, days-1).Format("2006-01-02")
+	}
+	if len(bookings) == 0 {
+		return header + "\n\nПриёмов нет."
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header + "\n")
+	for i, b := range bookings {
+		date := b.DateTime[:10]
+		if days > 1 && (i == 0 || date != bookings[i-1].DateTime[:10]) {
+			day, _ := time.Parse("2006-01-02", date)
+			sb.WriteString("\n" + weekdayNames[day.Weekday()] + ", " + date + "\n")
+		} else if i == 0 {
+			sb.WriteString("\n")
+		}
+		line := timeOf(b.DateTime) + " " + b.Name + " — " + b.Service + " · " + model.StatusTitle(b.Status)
+		if b.VisitNote != "" {
+			line += " 📝"
+		}
+		if sb.Len()+len(line) > messageLimit {
+			sb.WriteString("…")
+			break
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// visitButtons — кнопки со временем приёма, открывающие его карточку
+func visitButtons(bookings []*model.Booking, withDate bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, b := range bookings {
+		if i == visitButtonsLimit {
+			break
+		}
+		label := timeOf(b.DateTime)
+		if withDate {
+			label = b.DateTime[8:10] + "." + b.DateTime[5:7] + " " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "visit:"+strconv.Itoa(b.ID)))
+		if len(row) == visitButtonsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// formatVisitCard — приём глазами врача
+func formatVisitCard(b *model.Booking) string {
+	text := "Приём #" + strconv.Itoa(b.ID) + "\n" +
+		"Дата и время: " + b.DateTime + "\n" +
+		"Пациент: " + b.Name + "\n" +
+		"Телефон: " + b.Phone + "\n" +
+		"Услуга: " + b.Service + "\n" +
+		"Статус: " + model.StatusTitle(b.Status)
+	if b.VisitNote != "" {
+		text += "\nЗаметка: " + b.VisitNote
+	}
+	return text
+}
+
+// visitKeyboard — отметки врача о приёме
+func visitKeyboard(b *model.Booking) tgbotapi.InlineKeyboardMarkup {
+	prefix := "visit:" + strconv.Itoa(b.ID) + ":"
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Приём состоялся", prefix+model.StatusCompleted),
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Неявка", prefix+model.StatusNoShow),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📝 Заметка", prefix+model.EditNote),
+		),
+	)
+}
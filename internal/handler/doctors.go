@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/web"
+)
+
+func (h *CommandHandler) handleAdminDoctors(ctx context.Context, chatID int64, userID int64) {
+	if !h.access.Can(chatID, userID, model.PermManageDoctors) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	doctors, err := h.doctorService.GetDoctors()
+	if err != nil {
+		reportError(ctx, "admin_doctors", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения списка врачей."))
+		return
+	}
+	if len(doctors) == 0 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Врачей пока нет. Добавьте: /admin_doctor_add Имя Фамилия"))
+		return
+	}
+
+	text := "👨‍⚕️ Врачи:\n"
+	for _, d := range doctors {
+		text += "\n" + d.Name
+		if h.config.PublicURL != "" {
+			text += "\nКалендарь: " + web.CalendarFeedURL(h.config.PublicURL, d.FeedToken)
+		}
+		text += "\n"
+	}
+	if h.config.PublicURL == "" {
+		text += "\nСсылки на календари появятся, когда будут заданы HTTP_ADDR и PUBLIC_URL."
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.DisableWebPagePreview = true
+	h.bot.Send(msg)
+}
+
+func (h *CommandHandler) handleAdminDoctorAdd(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermManageDoctors) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	name := strings.TrimSpace(args)
+	if name == "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Укажите имя врача: /admin_doctor_add Анна Петрова"))
+		return
+	}
+
+	doctor, err := h.doctorService.CreateDoctor(name)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка добавления врача. Возможно, врач с таким именем уже есть."))
+		return
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Врач «"+doctor.Name+"» добавлен."))
+}
+
+// sendDoctorChoice заменяет кнопки карточки заявки выбором врача
+func (h *CallbackHandler) sendDoctorChoice(ctx context.Context, callback *tgbotapi.CallbackQuery, bookingID int) {
+	doctors, err := h.doctorService.GetDoctors()
+	if err != nil {
+		reportError(ctx, "doctor_choice", err)
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения списка врачей."))
+		return
+	}
+	if len(doctors) == 0 {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Врачей нет, добавьте их через /admin_doctor_add."))
+		return
+	}
+
+	prefix := "doctor:" + strconv.Itoa(bookingID) + ":"
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, d := range doctors {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(d.Name, prefix+strconv.FormatInt(d.ID, 10)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Без врача", prefix+"0"),
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Назад", "edit:"+strconv.Itoa(bookingID)+":"+editBack),
+	))
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	h.bot.Request(tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID,
+		tgbotapi.NewInlineKeyboardMarkup(rows...)))
+}
+
+// handleDoctorCallback назначает врача заявке: "doctor:ID заявки:ID врача"
+func (h *CallbackHandler) handleDoctorCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermManageBookings) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	bookingStr, doctorStr, _ := strings.Cut(strings.TrimPrefix(data, "doctor:"), ":")
+	bookingID, err1 := strconv.Atoi(bookingStr)
+	doctorID, err2 := strconv.ParseInt(doctorStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректные данные."))
+		return
+	}
+
+	if err := h.doctorService.AssignDoctor(bookingID, doctorID); err != nil {
+		if err == sql.ErrNoRows {
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Заявка с таким ID не найдена."))
+		} else {
+			reportError(ctx, "assign_doctor", err)
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка назначения врача."))
+		}
+		return
+	}
+
+	booking, err := h.bookingService.GetBookingByID(bookingID)
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения заявки."))
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, "Врач назначен."))
+	h.bot.Request(tgbotapi.NewEditMessageTextAndMarkup(callback.Message.Chat.ID, callback.Message.MessageID,
+		formatBookingCard(booking), bookingCardKeyboard(booking)))
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/logging"
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
+)
+
+// reportError записывает внутреннюю ошибку обработчика в лог и метрики.
+// Ошибки ввода пользователя сюда не относятся.
+func reportError(ctx context.Context, operation string, err error) {
+	logging.FromContext(ctx).Error("handler failed", "operation", operation, "error", err)
+	metrics.HandlerErrors.WithLabelValues(operation).Inc()
+}
@@ -0,0 +1,1029 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/config"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/replay"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegramtest"
+)
+
+const (
+	adminID     int64 = 1
+	patientID   int64 = 2
+	groupChatID int64 = -100
+	// groupMemberID состоит в админ-группе, но роли не имеет
+	groupMemberID int64 = 20
+
+	// Понедельник и воскресенье после testNow: рабочие часы 09:00-18:00 и выходной
+	monday = "2030-01-07"
+	sunday = "2030-01-06"
+)
+
+// testNow — текущий момент для обработчиков в тестах, вторник перед monday
+var testNow = time.Date(2030, 1, 1, 9, 0, 0, 0, time.Local)
+
+type testBot struct {
+	t         *testing.T
+	db        *sql.DB
+	sender    *replay.Capture
+	commands  *CommandHandler
+	callbacks *CallbackHandler
+	bookings  *service.BookingService
+	sessions  Sessions
+	members   groupMembers
+	// now — текущий момент для обработчиков, сначала testNow
+	now time.Time
+}
+
+func newTestBot(t *testing.T) *testBot {
+	t.Helper()
+
+	logger := slog.New(slog.DiscardHandler)
+	db, err := repository.InitDB(repository.InMemory, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := &config.Config{
+		AdminGroupChatID: groupChatID,
+		AdminGroupRole:   model.RoleAdmin,
+		AdminUserIDs:     []int64{adminID},
+		BotUserName:      "test_bot",
+	}
+	sender := &replay.Capture{}
+	bookings := service.NewBookingService(db, logger)
+	doctors := service.NewDoctorService(db)
+	patients := service.NewPatientService(db, logger)
+	waitlist := service.NewWaitlistService(db, 15*time.Minute, logger)
+	members := groupMembers{groupMemberID: true}
+	access := service.NewAccessService(db, cfg.AdminUserIDs, service.GroupAccess{
+		ChatID:  groupChatID,
+		Role:    cfg.AdminGroupRole,
+		Members: members,
+	}, logger)
+	sessions := make(Sessions)
+
+	bot := &testBot{
+		t:        t,
+		db:       db,
+		sender:   sender,
+		bookings: bookings,
+		sessions: sessions,
+		members:  members,
+		now:      testNow,
+	}
+	clock := func() time.Time { return bot.now }
+	bot.commands = NewCommandHandler(sender, groupChatID, bookings, doctors, patients, access, sessions, cfg).WithClock(clock)
+	bot.callbacks = NewCallbackHandler(sender, bookings, doctors, patients, waitlist, access, cfg, sessions).WithClock(clock)
+	return bot
+}
+
+// message обрабатывает сообщение пользователя в личном чате и возвращает ответы бота
+func (b *testBot) message(userID int64, text string) []telegram.Outgoing {
+	b.commands.HandleMessage(b.t.Context(), telegramtest.Message(userID, userID, text))
+	return b.sender.Take()
+}
+
+// press нажимает inline-кнопку под сообщением messageText
+func (b *testBot) press(userID int64, messageText, data string) []telegram.Outgoing {
+	b.callbacks.HandleCallback(b.t.Context(), telegramtest.Callback(userID, userID, messageText, data))
+	return b.sender.Take()
+}
+
+// pressOn нажимает inline-кнопку под сообщением бота с ID messageID
+func (b *testBot) pressOn(userID int64, messageID int, data string) []telegram.Outgoing {
+	callback := telegramtest.Callback(userID, userID, "", data)
+	callback.Message.MessageID = messageID
+	b.callbacks.HandleCallback(b.t.Context(), callback)
+	return b.sender.Take()
+}
+
+// book записывает пациента через диалог и возвращает ID заявки
+func (b *testBot) book(userID int64, name, phone, datetime string) int {
+	b.t.Helper()
+	calls := b.message(userID, "🗓️ Записаться на приём")
+	if len(calls) > 0 && strings.Contains(calls[0].Text, "Записать вас как") {
+		b.message(userID, "Изменить")
+	}
+	for _, text := range []string{name, phone, "Чистка", datetime[:10]} {
+		b.message(userID, text)
+	}
+	b.press(userID, "Выберите удобное время:", "time:"+datetime)
+
+	found, err := b.bookings.SearchBookings(phone, 1)
+	if err != nil || len(found) != 1 {
+		b.t.Fatalf("booking for %s not saved: %v", phone, err)
+	}
+	return found[0].ID
+}
+
+// outbox возвращает уведомления, ожидающие отправки диспетчером
+func (b *testBot) outbox() []*model.Notification {
+	b.t.Helper()
+	pending, err := repository.GetNotifications(b.db, model.NotificationPending)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return pending
+}
+
+// groupMessage обрабатывает сообщение пользователя в админ-группе
+func (b *testBot) groupMessage(userID int64, text string) []telegram.Outgoing {
+	b.commands.HandleMessage(b.t.Context(), telegramtest.Message(groupChatID, userID, text))
+	return b.sender.Take()
+}
+
+// groupMembers — участники админ-группы
+type groupMembers map[int64]bool
+
+func (m groupMembers) IsMember(chatID, userID int64) (bool, error) {
+	return chatID == groupChatID && m[userID], nil
+}
+
+func requireCall(t *testing.T, calls []telegram.Outgoing, method, text string) telegram.Outgoing {
+	t.Helper()
+	for _, c := range calls {
+		if c.Method == method && strings.Contains(c.Text, text) {
+			return c
+		}
+	}
+	t.Fatalf("no %s containing %q among %d calls:\n%s", method, text, len(calls), describe(calls))
+	return telegram.Outgoing{}
+}
+
+func describe(calls []telegram.Outgoing) string {
+	var sb strings.Builder
+	for _, c := range calls {
+		sb.WriteString(c.String() + "\n")
+	}
+	return sb.String()
+}
+
+func TestBookingDialogue(t *testing.T) {
+	bot := newTestBot(t)
+
+	steps := []struct {
+		name    string
+		input   string
+		want    string
+		buttons []string
+	}{
+		{"start", "🗓️ Записаться на приём", "Как вас зовут?", nil},
+		{"name", "Иван Петров", "номер телефона", nil},
+		{"phone", "+7 999 123-45-67", "Какую услугу", nil},
+		{"service", "Чистка", "На какую дату", nil},
+		{"bad date", "завтра", "Неверный формат даты", nil},
+		{"day off", sunday, "нет доступного времени", nil},
+		{"date", monday, "Выберите удобное время", []string{"time:" + monday + " 09:00", "time:" + monday + " 17:30"}},
+	}
+	for _, step := range steps {
+		calls := bot.message(patientID, step.input)
+		c := requireCall(t, calls, "sendMessage", step.want)
+		for _, data := range step.buttons {
+			if !slices.Contains(c.Buttons, data) {
+				t.Errorf("%s: no button %q in %v", step.name, data, c.Buttons)
+			}
+		}
+	}
+
+	calls := bot.press(patientID, "Выберите удобное время:", "time:"+monday+" 10:00")
+	requireCall(t, calls, "sendMessage", "Спасибо за запись")
+	requireCall(t, calls, "sendDocument", "Добавьте приём в свой календарь")
+	for _, c := range calls {
+		if c.ChatID == groupChatID {
+			t.Errorf("admin notification sent directly instead of through the outbox: %s", c)
+		}
+	}
+	requireCall(t, calls, "deleteMessage", "")
+	if _, ok := bot.sessions[SessionKey{ChatID: patientID, UserID: patientID}]; ok {
+		t.Error("session not cleared after booking")
+	}
+
+	saved, err := bot.bookings.SearchBookings("9991234567", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 1 || saved[0].Name != "Иван Петров" || saved[0].DateTime != monday+" 10:00" || saved[0].Status != model.StatusNew {
+		t.Fatalf("unexpected saved bookings: %+v", saved)
+	}
+
+	pending := bot.outbox()
+	if len(pending) != 1 || pending[0].ChatID != groupChatID || pending[0].BookingID != saved[0].ID ||
+		!strings.Contains(pending[0].Text, "Новая запись на приём") {
+		t.Fatalf("unexpected outbox: %+v", pending)
+	}
+
+	// Занятое время больше не предлагается, а устаревшая кнопка не создаёт вторую запись
+	const other int64 = 3
+	for _, text := range []string{"🗓️ Записаться на приём", "Анна", "+7 900 000-00-00", "Чистка"} {
+		bot.message(other, text)
+	}
+	c := requireCall(t, bot.message(other, monday), "sendMessage", "Выберите удобное время")
+	if slices.Contains(c.Buttons, "time:"+monday+" 10:00") {
+		t.Error("booked slot is still offered")
+	}
+	requireCall(t, bot.press(other, "Выберите удобное время:", "time:"+monday+" 10:00"), "answerCallbackQuery", "уже занято")
+	if pending := bot.outbox(); len(pending) != 1 {
+		t.Errorf("failed booking left a notification in the outbox: %+v", pending)
+	}
+
+	// Кнопка времени, нажатая после того, как оно наступило, запись не создаёт
+	bot.now = time.Date(2030, 1, 7, 11, 10, 0, 0, time.Local)
+	requireCall(t, bot.press(other, "Выберите удобное время:", "time:"+monday+" 11:00"), "answerCallbackQuery", "уже прошло")
+	if found, err := bot.bookings.SearchBookings("Анна", 10); err != nil || len(found) > 0 {
+		t.Errorf("booking saved for a past time: %+v, %v", found, err)
+	}
+}
+
+func TestAdminNewBooking(t *testing.T) {
+	bot := newTestBot(t)
+
+	requireCall(t, bot.message(adminID, "/admin_new"), "sendMessage", "Имя пациента")
+	requireCall(t, bot.message(adminID, "Мария"), "sendMessage", "Телефон пациента")
+	requireCall(t, bot.message(adminID, "+7 911 222-33-44"), "sendMessage", "Услуга")
+	requireCall(t, bot.message(adminID, "Пломба"), "sendMessage", "Дата приёма")
+	requireCall(t, bot.message(adminID, monday), "sendMessage", "Выберите удобное время")
+
+	calls := bot.press(adminID, "Выберите удобное время:", "time:"+monday+" 11:00")
+	requireCall(t, calls, "sendMessage", "Пациент записан")
+	if pending := bot.outbox(); len(pending) != 1 || !strings.Contains(pending[0].Text, "оформил администратор") {
+		t.Errorf("unexpected outbox: %+v", pending)
+	}
+	for _, c := range calls {
+		if c.Method == "sendDocument" {
+			t.Error("calendar file sent to the admin instead of the patient")
+		}
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	bot := newTestBot(t)
+	id := bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+
+	tests := []struct {
+		name   string
+		userID int64
+		text   string
+		want   string
+	}{
+		{"help", adminID, "/admin_help", "Доступные админ-команды"},
+		{"help denied", patientID, "/admin_help", "нет прав"},
+		{"list", adminID, "/admin_list", "Иван Петров"},
+		{"list denied", patientID, "/admin_list", "нет прав"},
+		{"list bad filter", adminID, "/admin_list status=unknown", "Ошибка в фильтре"},
+		{"stats", adminID, "/admin_stats", "Статистика заявок"},
+		{"stats bad period", adminID, "/admin_stats decade", "неизвестный период"},
+		{"find by name", adminID, "/admin_find петров", "ID: " + strconv.Itoa(id)},
+		{"find by phone", adminID, "/admin_find 123-45", "Имя: Иван Петров"},
+		{"find too short", adminID, "/admin_find ив", "Слишком короткий запрос"},
+		{"find nothing", adminID, "/admin_find Сидоров", "ничего не найдено"},
+		{"find denied", patientID, "/admin_find петров", "нет прав"},
+		{"doctor add", adminID, "/admin_doctor_add Анна Смирнова", "Врач «Анна Смирнова» добавлен"},
+		{"doctor add empty", adminID, "/admin_doctor_add", "Укажите имя врача"},
+		{"delete bad id", adminID, "/admin_delete abc", "корректный ID"},
+		{"delete missing", adminID, "/admin_delete 999", "не найдена"},
+		{"delete denied", patientID, "/admin_delete " + strconv.Itoa(id), "нет прав"},
+		{"unknown", adminID, "/nope", "Неизвестная команда"},
+		{"admin menu", adminID, "привет", "Администратор"},
+		{"patient menu", patientID, "привет", "Выберите действие"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireCall(t, bot.message(tt.userID, tt.text), "sendMessage", tt.want)
+		})
+	}
+
+	requireCall(t, bot.message(adminID, "/admin_delete "+strconv.Itoa(id)), "sendMessage", "Заявка отменена")
+	if b, err := bot.bookings.GetBookingByID(id); err != nil || b.Status != model.StatusCancelled || b.Sequence == 0 {
+		t.Errorf("booking after /admin_delete: %+v, %v", b, err)
+	}
+	requireCall(t, bot.message(adminID, "/admin_delete "+strconv.Itoa(id)), "sendMessage", "Заявка отменена")
+}
+
+func TestParseListFilter(t *testing.T) {
+	now := time.Date(2030, 1, 7, 15, 0, 0, 0, time.Local)
+	tests := []struct {
+		args string
+		want model.BookingFilter
+		text string // formatListFilter(want)
+	}{
+		{"", model.BookingFilter{}, listFilterNone},
+		{"today", model.BookingFilter{DateFrom: "2030-01-07", DateTo: "2030-01-07"}, "2030-01-07"},
+		{"завтра", model.BookingFilter{DateFrom: "2030-01-08", DateTo: "2030-01-08"}, "2030-01-08"},
+		{"week", model.BookingFilter{DateFrom: "2030-01-07", DateTo: "2030-01-13"}, "2030-01-07..2030-01-13"},
+		{"2030-02-01..2030-02-10 status:new", model.BookingFilter{DateFrom: "2030-02-01", DateTo: "2030-02-10", Status: "new"},
+			"2030-02-01..2030-02-10 status:new"},
+		{"service:чистка зубов doctor:Анна Смирнова", model.BookingFilter{Service: "чистка зубов", Doctor: "Анна Смирнова"},
+			"service:чистка зубов doctor:Анна Смирнова"},
+		{"Status:cancelled 2030-01-09", model.BookingFilter{DateFrom: "2030-01-09", DateTo: "2030-01-09", Status: "cancelled"},
+			"2030-01-09 status:cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := parseListFilter(tt.args, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if text := formatListFilter(got); text != tt.text {
+				t.Errorf("formatListFilter = %q, want %q", text, tt.text)
+			}
+		})
+	}
+
+	for _, args := range []string{"status:done", "2030-02-10..2030-02-01", "2030-02..2030-03", "вчера"} {
+		if _, err := parseListFilter(args, now); err == nil {
+			t.Errorf("%q: no error", args)
+		}
+	}
+}
+
+// TestListFilter проверяет, что листание сохраняет фильтр, с которым список
+// был запрошен, независимо от текста сообщения
+func TestListFilter(t *testing.T) {
+	bot := newTestBot(t)
+	for i := range listPageSize + 2 {
+		svc := "Чистка"
+		if i == 0 {
+			svc = "Пломба"
+		}
+		b := &model.Booking{Name: "Пациент " + strconv.Itoa(i), Phone: "+7 900 000-00-" + strconv.Itoa(10+i),
+			Service: svc, DateTime: monday + " " + time.Date(0, 1, 1, 9, 30*i, 0, 0, time.UTC).Format("15:04")}
+		if err := repository.SaveBooking(bot.db, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := requireCall(t, bot.message(adminID, "/admin_list service:чистка"), "sendMessage", "(стр. 1/2)")
+	if strings.Contains(first.Text, "Пломба") {
+		t.Errorf("filtered list shows other services:\n%s", first.Text)
+	}
+	listID := bot.sender.LastMessageID()
+
+	second := requireCall(t, bot.pressOn(adminID, listID, "list:1"), "editMessageText", "(стр. 2/2)")
+	if !strings.Contains(second.Text, "Заявки 11–11 из 11") || strings.Contains(second.Text, "Пломба") {
+		t.Errorf("second page lost the filter:\n%s", second.Text)
+	}
+
+	// Список без фильтра листается отдельно от отфильтрованного
+	bot.message(adminID, "/admin_list")
+	requireCall(t, bot.pressOn(adminID, bot.sender.LastMessageID(), "list:1"), "editMessageText", "Заявки 11–12 из 12")
+	requireCall(t, bot.pressOn(adminID, listID, "list:0"), "editMessageText", "Заявки 1–10 из 11")
+	requireCall(t, bot.pressOn(adminID, listID+100, "list:0"), "answerCallbackQuery", "Список устарел")
+}
+
+func TestCallbacks(t *testing.T) {
+	bot := newTestBot(t)
+	id := bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+	idStr := strconv.Itoa(id)
+	listText := "📋 Заявки\n" + listFilterPrefix + listFilterNone
+
+	tests := []struct {
+		name   string
+		userID int64
+		data   string
+		method string
+		want   string
+	}{
+		{"card", adminID, "card:" + idStr, "sendMessage", "Имя: Иван Петров"},
+		{"card missing", adminID, "card:999", "answerCallbackQuery", "не найдена"},
+		{"card denied", patientID, "card:" + idStr, "answerCallbackQuery", "нет прав"},
+		{"list stale", adminID, "list:0", "answerCallbackQuery", "Список устарел"},
+		{"list bad page", adminID, "list:-1", "answerCallbackQuery", "Некорректная страница"},
+		{"edit fields", adminID, "edit:" + idStr, "editMessageReplyMarkup", ""},
+		{"edit denied", patientID, "edit:" + idStr, "answerCallbackQuery", "нет прав"},
+		{"edit unknown field", adminID, "edit:" + idStr + ":color", "answerCallbackQuery", "Неизвестное поле"},
+		{"delete denied", patientID, "delete:" + idStr, "answerCallbackQuery", "нет прав"},
+		{"stale time button", patientID, "time:" + monday + " 12:00", "answerCallbackQuery", "сессия бронирования не найдена"},
+		{"unknown", adminID, "bogus", "answerCallbackQuery", "Неизвестный callback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireCall(t, bot.press(tt.userID, listText, tt.data), tt.method, tt.want)
+		})
+	}
+
+	t.Run("edit name", func(t *testing.T) {
+		requireCall(t, bot.press(adminID, "", "edit:"+idStr+":"+model.EditName), "sendMessage", "Новое имя пациента")
+		requireCall(t, bot.message(adminID, "Иван Сидоров"), "sendMessage", "изменена")
+
+		b, err := bot.bookings.GetBookingByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Name != "Иван Сидоров" || b.Sequence != 1 {
+			t.Errorf("got name %q sequence %d after edit", b.Name, b.Sequence)
+		}
+	})
+
+	t.Run("reschedule", func(t *testing.T) {
+		bot.press(adminID, "", "edit:"+idStr+":"+model.EditTime)
+		requireCall(t, bot.message(adminID, monday), "sendMessage", "Выберите удобное время")
+		requireCall(t, bot.press(adminID, "Выберите удобное время:", "time:"+monday+" 15:00"), "sendMessage", "перенесена")
+
+		b, err := bot.bookings.GetBookingByID(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.DateTime != monday+" 15:00" {
+			t.Errorf("booking time is %s after reschedule", b.DateTime)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		calls := bot.press(adminID, "", "delete:"+idStr)
+		requireCall(t, calls, "answerCallbackQuery", "Заявка отменена")
+		requireCall(t, calls, "deleteMessage", "")
+		if b, err := bot.bookings.GetBookingByID(id); err != nil || b.Status != model.StatusCancelled {
+			t.Errorf("booking after delete button: %+v, %v", b, err)
+		}
+	})
+}
+
+func TestStaffRoles(t *testing.T) {
+	bot := newTestBot(t)
+	const receptionistID int64 = 5
+	receptionist := strconv.FormatInt(receptionistID, 10)
+
+	steps := []struct {
+		name   string
+		userID int64
+		text   string
+		want   string
+	}{
+		{"no role yet", receptionistID, "/admin_list", "нет прав"},
+		{"grant denied to patient", patientID, "/admin_grant " + receptionist + " admin", "нет прав"},
+		{"grant usage", adminID, "/admin_grant " + receptionist + " boss", "Использование"},
+		{"grant", adminID, "/admin_grant " + receptionist + " receptionist Анна", "выдана роль «Регистратор»"},
+		{"staff list", adminID, "/admin_staff", receptionist + " — Регистратор, Анна"},
+		{"receptionist menu", receptionistID, "привет", "Администратор"},
+		{"receptionist lists", receptionistID, "/admin_list", "Заявок не найдено"},
+		{"receptionist books", receptionistID, "/admin_new", "Имя пациента"},
+		{"receptionist has no stats", receptionistID, "/admin_stats", "нет прав"},
+		{"receptionist cannot grant", receptionistID, "/admin_grant 6 owner", "нет прав"},
+		{"bootstrap owner stays", adminID, "/admin_revoke " + strconv.FormatInt(adminID, 10), "ADMIN_USER_IDS"},
+		{"revoke", adminID, "/admin_revoke " + receptionist, "отозвана"},
+		{"revoke again", adminID, "/admin_revoke " + receptionist, "нет роли"},
+		{"role revoked", receptionistID, "/admin_list", "нет прав"},
+	}
+	for _, step := range steps {
+		requireCall(t, bot.message(step.userID, step.text), "sendMessage", step.want)
+	}
+}
+
+func TestAdminHelpByRole(t *testing.T) {
+	bot := newTestBot(t)
+	const doctorID int64 = 7
+	bot.message(adminID, "/admin_doctor_add Анна Смирнова")
+	requireCall(t, bot.message(adminID, "/admin_grant 7 doctor Анна Смирнова"), "sendMessage", "выдана роль")
+
+	help := requireCall(t, bot.message(doctorID, "/admin_help"), "sendMessage", "ваша роль: Врач")
+	if !strings.Contains(help.Text, doctorDayButton) || strings.Contains(help.Text, "/admin_list") {
+		t.Errorf("doctor help lists wrong commands:\n%s", help.Text)
+	}
+	help = requireCall(t, bot.message(adminID, "/admin_help"), "sendMessage", "ваша роль: Владелец")
+	if !strings.Contains(help.Text, "/admin_grant") {
+		t.Errorf("owner help has no staff commands:\n%s", help.Text)
+	}
+}
+
+func TestDoctorMode(t *testing.T) {
+	bot := newTestBot(t)
+	const doctorID, otherDoctorID int64 = 7, 8
+
+	requireCall(t, bot.message(adminID, "/admin_grant 7 doctor Анна Смирнова"), "sendMessage", "не найден")
+	bot.message(adminID, "/admin_doctor_add Анна Смирнова")
+	bot.message(adminID, "/admin_doctor_add Борис Орлов")
+	requireCall(t, bot.message(adminID, "/admin_grant 7 doctor анна смирнова"), "sendMessage", "выдана роль «Врач»")
+	bot.message(adminID, "/admin_grant 8 doctor Борис Орлов")
+
+	// Приём сегодня в 00:00 уже начался, его итог можно отметить
+	today := testNow.Format("2006-01-02")
+	started := &model.Booking{Name: "Иван Петров", Phone: "+7 999 123-45-67", Service: "Чистка", DateTime: today + " 00:00"}
+	if err := repository.SaveBooking(bot.db, started); err != nil {
+		t.Fatal(err)
+	}
+	future := bot.book(patientID, "Мария", "+7 911 222-33-44", monday+" 10:00")
+	for _, id := range []int{started.ID, future} {
+		if err := repository.AssignDoctor(bot.db, id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	startedID, futureID := strconv.Itoa(started.ID), strconv.Itoa(future)
+
+	requireCall(t, bot.message(doctorID, "привет"), "sendMessage", "Доктор")
+	day := requireCall(t, bot.message(doctorID, doctorDayButton), "sendMessage", "Иван Петров — Чистка · Новая")
+	if !slices.Contains(day.Buttons, "visit:"+startedID) || strings.Contains(day.Text, "Мария") {
+		t.Errorf("unexpected day schedule %q with buttons %v", day.Text, day.Buttons)
+	}
+	requireCall(t, bot.message(otherDoctorID, doctorDayButton), "sendMessage", "Приёмов нет")
+	requireCall(t, bot.message(doctorID, "/admin_list"), "sendMessage", "нет прав")
+
+	steps := []struct {
+		name   string
+		userID int64
+		data   string
+		method string
+		want   string
+	}{
+		{"card", doctorID, "visit:" + startedID, "sendMessage", "Пациент: Иван Петров"},
+		{"other doctor", otherDoctorID, "visit:" + startedID, "answerCallbackQuery", "другого врача"},
+		{"patient", patientID, "visit:" + startedID + ":" + model.StatusCompleted, "answerCallbackQuery", "нет прав"},
+		{"not started", doctorID, "visit:" + futureID + ":" + model.StatusNoShow, "answerCallbackQuery", "ещё не начался"},
+		{"completed", doctorID, "visit:" + startedID + ":" + model.StatusCompleted, "editMessageText", "Статус: Завершена"},
+		{"note", doctorID, "visit:" + startedID + ":" + model.EditNote, "sendMessage", "Заметка о приёме"},
+	}
+	for _, step := range steps {
+		requireCall(t, bot.press(step.userID, "", step.data), step.method, step.want)
+	}
+	requireCall(t, bot.message(doctorID, "Рекомендована повторная чистка через полгода"), "sendMessage", "Заметка сохранена")
+
+	b, err := bot.bookings.GetBookingByID(started.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Status != model.StatusCompleted || !strings.Contains(b.VisitNote, "повторная чистка") {
+		t.Errorf("visit not recorded: status %s, note %q", b.Status, b.VisitNote)
+	}
+	requireCall(t, bot.press(adminID, "", "card:"+startedID), "sendMessage", "Заметка врача: Рекомендована")
+
+	week := requireCall(t, bot.message(doctorID, doctorWeekButton), "sendMessage", "Мои приёмы с "+today)
+	if !strings.Contains(week.Text, "📝") {
+		t.Errorf("week schedule does not mark the note:\n%s", week.Text)
+	}
+}
+
+func TestAdminGroup(t *testing.T) {
+	bot := newTestBot(t)
+	bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+
+	tests := []struct {
+		name   string
+		userID int64
+		text   string
+		want   string // "" — бот молчит
+	}{
+		{"member", groupMemberID, "/admin_list", "Иван Петров"},
+		{"addressed to us", groupMemberID, "/admin_list@Test_Bot", "Иван Петров"},
+		{"addressed to another bot", groupMemberID, "/admin_list@other_bot", ""},
+		{"member cannot grant", groupMemberID, "/admin_grant 5 admin", "нет прав"},
+		{"not a member", patientID, "/admin_list", "нет прав"},
+		{"owner", adminID, "/admin_grant 5 admin", "выдана роль"},
+		{"unknown command", groupMemberID, "/start", ""},
+		{"unknown command to us", groupMemberID, "/start@test_bot", "Неизвестная команда"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := bot.groupMessage(tt.userID, tt.text)
+			if tt.want == "" {
+				if len(calls) > 0 {
+					t.Fatalf("expected no reply, got:\n%s", describe(calls))
+				}
+				return
+			}
+			if c := requireCall(t, calls, "sendMessage", tt.want); c.ChatID != groupChatID {
+				t.Errorf("reply sent to %d", c.ChatID)
+			}
+		})
+	}
+
+	// Членство в группе не даёт прав в личном чате
+	requireCall(t, bot.message(groupMemberID, "/admin_list"), "sendMessage", "нет прав")
+}
+
+func TestGroupSessions(t *testing.T) {
+	bot := newTestBot(t)
+
+	// Сессия в группе своя у каждого: сообщения и кнопки коллег в неё не попадают
+	requireCall(t, bot.groupMessage(adminID, "/admin_new"), "sendMessage", "Имя пациента")
+	if calls := bot.groupMessage(groupMemberID, "Посторонний текст"); len(calls) > 0 && strings.Contains(calls[0].Text, "Телефон") {
+		t.Fatal("a colleague's message went into the admin's session")
+	}
+	requireCall(t, bot.groupMessage(adminID, "Мария"), "sendMessage", "Телефон пациента")
+	for _, text := range []string{"+7 911 222-33-44", "Пломба"} {
+		bot.groupMessage(adminID, text)
+	}
+	requireCall(t, bot.groupMessage(adminID, monday), "sendMessage", "Выберите удобное время")
+	requireCall(t, bot.groupPress(groupMemberID, "time:"+monday+" 12:00"), "answerCallbackQuery", "сессия бронирования не найдена")
+	requireCall(t, bot.groupPress(adminID, "time:"+monday+" 12:00"), "sendMessage", "Пациент записан")
+
+	found, err := bot.bookings.SearchBookings("9112223344", 1)
+	if err != nil || len(found) != 1 || found[0].Name != "Мария" {
+		t.Fatalf("booking not saved as entered by the admin: %v %v", found, err)
+	}
+
+	// Изменение заявки продолжает только тот, кто его начал
+	id := strconv.Itoa(found[0].ID)
+	requireCall(t, bot.groupPress(adminID, "edit:"+id+":"+model.EditName), "sendMessage", "Новое имя пациента")
+	bot.groupMessage(groupMemberID, "Подмена")
+	requireCall(t, bot.groupMessage(adminID, "Мария Иванова"), "sendMessage", "изменена")
+
+	// Сессия не переживает потерю прав
+	requireCall(t, bot.groupMessage(groupMemberID, "/admin_new"), "sendMessage", "Имя пациента")
+	delete(bot.members, groupMemberID)
+	if calls := bot.groupMessage(groupMemberID, "Пётр"); len(calls) > 0 && strings.Contains(calls[0].Text, "Телефон") {
+		t.Fatal("session continued after the group membership was lost")
+	}
+	if len(bot.sessions) != 0 {
+		t.Errorf("sessions left: %v", bot.sessions)
+	}
+}
+
+// groupPress обрабатывает нажатие кнопки под сообщением в админ-группе
+func (b *testBot) groupPress(userID int64, data string) []telegram.Outgoing {
+	b.callbacks.HandleCallback(b.t.Context(), telegramtest.Callback(groupChatID, userID, "", data))
+	return b.sender.Take()
+}
+
+func TestNotificationActions(t *testing.T) {
+	bot := newTestBot(t)
+	bookingID := bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+	id := strconv.Itoa(bookingID)
+
+	// Уведомление уходит с кнопками; отмечаем его доставленным, как диспетчер
+	pending := bot.outbox()
+	if len(pending) != 1 || !strings.Contains(pending[0].ReplyMarkup, "booking:"+id+":confirm") {
+		t.Fatalf("unexpected outbox: %+v", pending)
+	}
+	const notificationMessageID = 555
+	if err := repository.MarkNotificationSent(bot.db, pending[0].ID, notificationMessageID); err != nil {
+		t.Fatal(err)
+	}
+
+	requireCall(t, bot.groupPress(patientID, "booking:"+id+":confirm"), "answerCallbackQuery", "нет прав")
+	requireCall(t, bot.groupPress(groupMemberID, "booking:"+id+":call"), "sendMessage", "Иван Петров: +7 999 123-45-67")
+
+	calls := bot.groupPress(groupMemberID, "booking:"+id+":confirm")
+	requireCall(t, calls, "answerCallbackQuery", "подтверждена")
+	edit := requireCall(t, calls, "editMessageText", "Статус: Подтверждена\n\n✅ Подтвердил(а) User")
+	if edit.ChatID != groupChatID || edit.MessageID != notificationMessageID {
+		t.Errorf("edited %d/%d", edit.ChatID, edit.MessageID)
+	}
+	if slices.Contains(edit.Buttons, "booking:"+id+":confirm") || !slices.Contains(edit.Buttons, "booking:"+id+":cancel") {
+		t.Errorf("unexpected buttons after confirm: %v", edit.Buttons)
+	}
+
+	// Перенос через кнопку переписывает уведомление с новым временем
+	requireCall(t, bot.groupPress(groupMemberID, "edit:"+id+":time"), "sendMessage", "Новая дата")
+	bot.commands.HandleMessage(t.Context(), telegramtest.Message(groupChatID, groupMemberID, monday))
+	bot.sender.Take()
+	calls = bot.groupPress(groupMemberID, "time:"+monday+" 12:00")
+	edit = requireCall(t, calls, "editMessageText", "🔁 Перенёс(ла) User")
+	if !strings.Contains(edit.Text, "Дата и время: "+monday+" 12:00") || !strings.HasPrefix(edit.Text, "Новая запись на приём:") {
+		t.Errorf("notification not updated:\n%s", edit.Text)
+	}
+
+	calls = bot.groupPress(groupMemberID, "booking:"+id+":cancel")
+	edit = requireCall(t, calls, "editMessageText", "Статус: Отменена\n\n❌ Отменил(а) User")
+	if want := []string{"booking:" + id + ":call", "card:" + id}; !slices.Equal(edit.Buttons, want) {
+		t.Errorf("buttons after cancel: %v, want %v", edit.Buttons, want)
+	}
+	if booking, _ := bot.bookings.GetBookingByID(bookingID); booking.Status != model.StatusCancelled {
+		t.Errorf("booking status %s", booking.Status)
+	}
+
+	requireCall(t, bot.groupPress(groupMemberID, "booking:"+id+":confirm"), "answerCallbackQuery", "уже закрыта")
+}
+
+func TestPatientProfile(t *testing.T) {
+	bot := newTestBot(t)
+
+	requireCall(t, bot.message(patientID, "/profile"), "sendMessage", "появится после первой записи")
+	bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+
+	// Повторная запись не спрашивает имя и телефон
+	requireCall(t, bot.message(patientID, "🗓️ Записаться на приём"), "sendMessage",
+		"Записать вас как Иван Петров, +7 999 123-45-67?")
+	requireCall(t, bot.message(patientID, "что?"), "sendMessage", "Записать вас как")
+	requireCall(t, bot.message(patientID, "Да"), "sendMessage", "Какую услугу")
+	bot.message(patientID, "Пломба")
+	bot.message(patientID, monday)
+	bot.press(patientID, "Выберите удобное время:", "time:"+monday+" 11:00")
+
+	bookings, err := bot.bookings.GetAllBookings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 2 {
+		t.Fatalf("got %d bookings", len(bookings))
+	}
+	for _, b := range bookings {
+		if b.PatientID != patientID || b.Name != "Иван Петров" || b.Phone != "+7 999 123-45-67" {
+			t.Errorf("booking #%d: %+v", b.ID, b)
+		}
+	}
+
+	// «Изменить» спрашивает заново и обновляет профиль
+	bot.message(patientID, "🗓️ Записаться на приём")
+	requireCall(t, bot.message(patientID, "Изменить"), "sendMessage", "Как вас зовут?")
+	bot.message(patientID, "Иван Петров")
+	requireCall(t, bot.message(patientID, "+7 900 000-00-00"), "sendMessage", "Какую услугу")
+	bot.message(patientID, "Пломба")
+	bot.message(patientID, monday)
+	bot.press(patientID, "Выберите удобное время:", "time:"+monday+" 12:00")
+	requireCall(t, bot.message(patientID, "/profile"), "sendMessage", "Телефон: +7 900 000-00-00")
+
+	requireCall(t, bot.message(patientID, "/profile birth 1990-13-01"), "sendMessage", "Неверная дата рождения")
+	requireCall(t, bot.message(patientID, "/profile birth 1990-05-01"), "sendMessage", "Дата рождения: 1990-05-01")
+	requireCall(t, bot.message(patientID, "/profile wishes только утром"), "sendMessage", "Пожелания: только утром")
+	requireCall(t, bot.message(patientID, "/profile nickname x"), "sendMessage", "Изменить профиль")
+
+	// Запись, оформленная администратором, к профилю не привязывается
+	bot.message(adminID, "/admin_new")
+	for _, text := range []string{"Мария", "+7 911 222-33-44", "Пломба", monday} {
+		bot.message(adminID, text)
+	}
+	bot.press(adminID, "Выберите удобное время:", "time:"+monday+" 13:00")
+	found, err := bot.bookings.SearchBookings("Мария", 1)
+	if err != nil || len(found) != 1 || found[0].PatientID != 0 {
+		t.Errorf("admin booking: %+v, %v", found, err)
+	}
+}
+
+func TestPatientCard(t *testing.T) {
+	bot := newTestBot(t)
+	id := bot.book(patientID, "Иван Петров", "+7 999 123-45-67", monday+" 10:00")
+
+	// Прошедшие приёмы: неявка и приём с заметкой врача
+	for _, b := range []*model.Booking{
+		{Name: "Иван Петров", Phone: "+7 999 123-45-67", Service: "Чистка", DateTime: "2020-01-06 10:00", PatientID: patientID},
+		{Name: "Иван Петров", Phone: "8 999 123-45-67", Service: "Пломба", DateTime: "2020-02-03 11:00"},
+	} {
+		if err := repository.SaveBooking(bot.db, b); err != nil {
+			t.Fatal(err)
+		}
+		status := model.StatusNoShow
+		if b.PatientID == 0 {
+			status = model.StatusCompleted
+			if err := repository.SetVisitNote(bot.db, b.ID, "Чувствительная эмаль"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repository.SetBookingStatus(bot.db, b.ID, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Пациентка, которую записывал только администратор
+	bot.message(adminID, "/admin_new")
+	for _, text := range []string{"Мария", "+7 911 222-33-44", "Пломба", monday} {
+		bot.message(adminID, text)
+	}
+	bot.press(adminID, "Выберите удобное время:", "time:"+monday+" 11:00")
+
+	tests := []struct {
+		name   string
+		userID int64
+		text   string
+		want   []string
+	}{
+		{"by telegram id", adminID, "/admin_patient 2", []string{
+			"👤 Иван Петров", "Telegram ID: 2", "Заявок: 3 · отмен: 0 · неявок: 1",
+			"Предстоящие:\n#" + strconv.Itoa(id) + " · " + monday + " 10:00",
+			"Прошедшие:\n#3 · 2020-02-03 11:00 · Завершена · Пломба\n#2 · 2020-01-06 10:00 · Неявка",
+			"Заметки врачей:\n2020-02-03 11:00: Чувствительная эмаль",
+		}},
+		{"by phone", adminID, "/admin_patient 8 (999) 123-45-67", []string{"Telegram ID: 2"}},
+		{"without profile", adminID, "/admin_patient +7 911 222-33-44", []string{"👤 Мария", "Через бота сам не записывался"}},
+		{"not found", adminID, "/admin_patient 555", []string{"Пациент не найден"}},
+		{"usage", adminID, "/admin_patient Иван", []string{"Использование"}},
+		{"denied", patientID, "/admin_patient 2", []string{"нет прав"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := bot.message(tt.userID, tt.text)
+			for _, want := range tt.want {
+				requireCall(t, calls, "sendMessage", want)
+			}
+		})
+	}
+
+	calls := bot.message(adminID, "/admin_patient +7 911 222-33-44")
+	if buttons := requireCall(t, calls, "sendMessage", "Мария").Buttons; !slices.Equal(buttons, []string{"patient:79112223344:book"}) {
+		t.Errorf("buttons without profile: %v", buttons)
+	}
+
+	// Сообщение пациенту уходит от бота в его личный чат
+	requireCall(t, bot.press(adminID, "", "patient:2:msg"), "sendMessage", "Сообщение пациенту Иван Петров")
+	calls = bot.message(adminID, "Пожалуйста, возьмите снимки")
+	if c := requireCall(t, calls, "sendMessage", "Сообщение из клиники:\n\nПожалуйста, возьмите снимки"); c.ChatID != patientID {
+		t.Errorf("message sent to %d", c.ChatID)
+	}
+	requireCall(t, calls, "sendMessage", "Сообщение отправлено")
+	requireCall(t, bot.press(patientID, "", "patient:2:msg"), "answerCallbackQuery", "нет прав")
+
+	// В группе сообщение коллеги не уходит пациенту из чужой сессии «Написать»
+	requireCall(t, bot.groupPress(adminID, "patient:2:msg"), "sendMessage", "Сообщение пациенту Иван Петров")
+	for _, c := range bot.groupMessage(groupMemberID, "Текст не для пациента") {
+		if c.ChatID == patientID {
+			t.Fatalf("a colleague's message reached the patient: %s", c)
+		}
+	}
+	if c := requireCall(t, bot.groupMessage(adminID, "Ждём вас завтра"), "sendMessage", "Ждём вас завтра"); c.ChatID != patientID {
+		t.Errorf("message sent to %d", c.ChatID)
+	}
+
+	// Потерявший права сотрудник написать не может
+	requireCall(t, bot.groupPress(groupMemberID, "patient:2:msg"), "sendMessage", "Сообщение пациенту")
+	delete(bot.members, groupMemberID)
+	for _, c := range bot.groupMessage(groupMemberID, "Текст после отзыва прав") {
+		if c.ChatID == patientID {
+			t.Fatalf("message sent without rights: %s", c)
+		}
+	}
+
+	// Запись из карточки начинается с услуги и привязывается к пациенту
+	requireCall(t, bot.press(adminID, "", "patient:2:book"), "sendMessage", "Запись пациента Иван Петров, +7 999 123-45-67")
+	requireCall(t, bot.message(adminID, "Пломба"), "sendMessage", "Дата приёма")
+	bot.message(adminID, monday)
+	requireCall(t, bot.press(adminID, "Выберите удобное время:", "time:"+monday+" 12:00"), "sendMessage", "Пациент записан")
+	requireCall(t, bot.message(adminID, "/admin_patient 2"), "sendMessage", "Заявок: 4")
+}
+
+func TestNearestDates(t *testing.T) {
+	bot := newTestBot(t)
+
+	// Понедельник и вторник заняты целиком
+	for _, day := range []int{7, 8} {
+		for slot := time.Date(2030, 1, day, 9, 0, 0, 0, time.Local); slot.Hour() < 18; slot = slot.Add(repository.SlotDuration) {
+			b := &model.Booking{Name: "Пациент", Phone: "+7 900 000-00-00", Service: "Чистка", DateTime: slot.Format("2006-01-02 15:04")}
+			if err := repository.SaveBooking(bot.db, b); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, text := range []string{"🗓️ Записаться на приём", "Иван", "+7 999 123-45-67", "Чистка"} {
+		bot.message(patientID, text)
+	}
+	wantDates := []string{"date:2030-01-09", "date:2030-01-10", "date:2030-01-11"}
+
+	// Выходной — не ошибка: предлагаются ближайшие рабочие дни со свободным временем
+	sunday := requireCall(t, bot.message(patientID, "2030-01-06"), "sendMessage", "нет доступного времени")
+	if !slices.Equal(sunday.Buttons[:3], wantDates) {
+		t.Fatalf("date buttons %v, want %v", sunday.Buttons, wantDates)
+	}
+	full := requireCall(t, bot.message(patientID, monday), "sendMessage", "Ближайшие свободные даты")
+	if !slices.Equal(full.Buttons[:3], wantDates) {
+		t.Fatalf("date buttons %v, want %v", full.Buttons, wantDates)
+	}
+
+	// Кнопка даты сразу показывает свободное время
+	slots := requireCall(t, bot.press(patientID, full.Text, "date:2030-01-09"), "sendMessage", "Выберите удобное время:")
+	if len(slots.Buttons) == 0 || slots.Buttons[0] != "time:2030-01-09 09:00" {
+		t.Fatalf("time buttons: %v", slots.Buttons)
+	}
+	requireCall(t, bot.press(patientID, slots.Text, "time:2030-01-09 09:00"), "sendMessage", "Спасибо за запись")
+
+	// Без сессии записи кнопка даты устарела
+	requireCall(t, bot.press(patientID, full.Text, "date:2030-01-10"), "answerCallbackQuery", "сессия бронирования не найдена")
+
+	// На прошедшую дату время не предлагается, а подсказки начинаются не раньше сегодня
+	for _, text := range []string{"🗓️ Записаться на приём", "Да", "Чистка"} {
+		bot.message(patientID, text)
+	}
+	past := requireCall(t, bot.message(patientID, "2020-01-06"), "sendMessage", "нет доступного времени")
+	today := "date:" + testNow.Format("2006-01-02")
+	for _, data := range past.Buttons {
+		if strings.HasPrefix(data, "date:") && data < today {
+			t.Errorf("past date suggested: %s", data)
+		}
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	bot := newTestBot(t)
+	const otherPatientID int64 = 3
+
+	// Понедельник занят целиком
+	slotIDs := make(map[string]int)
+	for slot := time.Date(2030, 1, 7, 9, 0, 0, 0, time.Local); slot.Hour() < 18; slot = slot.Add(repository.SlotDuration) {
+		b := &model.Booking{Name: "Пациент", Phone: "+7 900 000-00-00", Service: "Чистка", DateTime: slot.Format("2006-01-02 15:04")}
+		if err := repository.SaveBooking(bot.db, b); err != nil {
+			t.Fatal(err)
+		}
+		slotIDs[b.DateTime[11:]] = b.ID
+	}
+
+	join := func(userID int64, name, dayPart string) {
+		t.Helper()
+		for _, text := range []string{"🗓️ Записаться на приём", name, "+7 999 000-00-0" + strconv.FormatInt(userID, 10), "Чистка"} {
+			bot.message(userID, text)
+		}
+		full := requireCall(t, bot.message(userID, monday), "sendMessage", "встаньте в лист ожидания")
+		if want := []string{"wait:" + monday + ":" + monday, "wait:" + monday + ":2030-01-13"}; len(full.Buttons) < 2 || !slices.Equal(full.Buttons[len(full.Buttons)-2:], want) {
+			t.Fatalf("waitlist buttons %v, want %v", full.Buttons, want)
+		}
+		parts := requireCall(t, bot.press(userID, full.Text, "wait:"+monday+":"+monday), "editMessageText", "Какое время вам удобно?")
+		if len(parts.Buttons) != len(model.DayParts) {
+			t.Fatalf("day part buttons: %v", parts.Buttons)
+		}
+		requireCall(t, bot.press(userID, parts.Text, "wait:"+monday+":"+monday+":"+dayPart), "sendMessage", "Вы в листе ожидания: "+monday)
+	}
+	join(patientID, "Иван", model.DayPartMorning)
+	join(otherPatientID, "Анна", model.DayPartAny)
+
+	now := testNow
+	offer := func(at time.Time) []telegram.Outgoing {
+		t.Helper()
+		if err := bot.callbacks.OfferFreedSlots(t.Context(), at); err != nil {
+			t.Fatal(err)
+		}
+		return bot.sender.Take()
+	}
+	if calls := offer(now); len(calls) > 0 {
+		t.Fatalf("offers for a full day:\n%s", describe(calls))
+	}
+
+	// Освободившееся утреннее время сначала предлагается тому, кто встал в лист раньше
+	if _, err := bot.bookings.SetStatus(slotIDs["10:00"], model.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	first := requireCall(t, offer(now), "sendMessage", "Освободилось время: "+monday+" 10:00")
+	if first.ChatID != patientID || !slices.Equal(first.Buttons, []string{"offer:1:claim", "offer:1:decline"}) {
+		t.Fatalf("first offer: %+v", first)
+	}
+	if calls := offer(now); len(calls) > 0 {
+		t.Fatalf("slot offered twice while the offer is open:\n%s", describe(calls))
+	}
+	// Пока предложение открыто, время придержано и другим не показывается
+	if slots, err := bot.bookings.GetAvailableTimeSlots(monday, now); err != nil || len(slots) > 0 {
+		t.Fatalf("offered slot shown to other bookers: %v, %v", slots, err)
+	}
+	if err := bot.bookings.ValidateDateTime(monday+" 10:00", now); !errors.Is(err, repository.ErrSlotUnavailable) {
+		t.Fatalf("offered slot can be booked by others: %v", err)
+	}
+	requireCall(t, bot.press(otherPatientID, first.Text, "offer:1:claim"), "answerCallbackQuery", "не найдено")
+
+	// Отказ передаёт время следующему; неотвеченное предложение истекает
+	requireCall(t, bot.press(patientID, first.Text, "offer:1:decline"), "editMessageText", "предложим другое время")
+	second := requireCall(t, offer(now), "sendMessage", "Освободилось время: "+monday+" 10:00")
+	if second.ChatID != otherPatientID {
+		t.Fatalf("second offer sent to %d", second.ChatID)
+	}
+	if calls := offer(now.Add(16 * time.Minute)); len(calls) > 0 {
+		t.Fatalf("slot offered again to the same patients:\n%s", describe(calls))
+	}
+	requireCall(t, bot.press(otherPatientID, second.Text, "offer:2:claim"), "editMessageText", "время истекло")
+
+	// Дневное время не подходит тому, кто ждёт утро
+	if _, err := bot.bookings.SetStatus(slotIDs["15:00"], model.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	third := requireCall(t, offer(now.Add(20*time.Minute)), "sendMessage", "Освободилось время: "+monday+" 15:00")
+	if third.ChatID != otherPatientID {
+		t.Fatalf("third offer sent to %d", third.ChatID)
+	}
+	calls := bot.press(otherPatientID, third.Text, "offer:3:claim")
+	requireCall(t, calls, "editMessageText", "Вы записаны на "+monday+" 15:00")
+	requireCall(t, calls, "sendDocument", "")
+
+	found, err := bot.bookings.SearchBookings("Анна", 1)
+	if err != nil || len(found) != 1 || found[0].PatientID != otherPatientID || found[0].DateTime != monday+" 15:00" {
+		t.Fatalf("claimed booking: %+v, %v", found, err)
+	}
+	if pending := bot.outbox(); len(pending) != 1 || pending[0].BookingID != found[0].ID {
+		t.Errorf("unexpected outbox: %+v", pending)
+	}
+	requireCall(t, bot.press(otherPatientID, third.Text, "offer:3:claim"), "editMessageText", "неактуально")
+
+	// Администратору лист ожидания не предлагается
+	bot.message(adminID, "/admin_new")
+	for _, text := range []string{"Мария", "+7 911 222-33-44", "Пломба"} {
+		bot.message(adminID, text)
+	}
+	if err := repository.SaveBooking(bot.db, &model.Booking{Name: "Пациент", Phone: "+7 900 000-00-00", Service: "Чистка", DateTime: monday + " 10:00"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := requireCall(t, bot.message(adminID, monday), "sendMessage", "нет доступного времени"); slices.ContainsFunc(c.Buttons, func(data string) bool { return strings.HasPrefix(data, "wait:") }) {
+		t.Errorf("admin offered the waitlist: %v", c.Buttons)
+	}
+
+	// Недоставленное предложение не придерживает время
+	if _, err := bot.bookings.SetStatus(slotIDs["11:00"], model.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+	failing := &telegramtest.FlakySender{Errs: []error{errors.New("connection reset")}}
+	later := now.Add(30 * time.Minute)
+	if err := bot.callbacks.WithSender(failing).OfferFreedSlots(t.Context(), later); err != nil || failing.Calls != 1 {
+		t.Fatalf("offer with a failing sender: %v, %d calls", err, failing.Calls)
+	}
+	if slots, err := bot.bookings.GetAvailableTimeSlots(monday, later); err != nil || !slices.Equal(slots, []string{monday + " 11:00"}) {
+		t.Errorf("undelivered offer holds the slot: %v, %v", slots, err)
+	}
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+	"github.com/REmakerzz/dental-clinic-bot/internal/ui"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// patientHistoryLimit — сколько прошедших приёмов показывать в карточке пациента
+const patientHistoryLimit = 10
+
+// Действия кнопок карточки пациента: "patient:ключ:действие"
+const (
+	patientMessage = "msg"
+	patientBook    = "book"
+)
+
+// handleAdminPatient показывает карточку пациента по Telegram ID или телефону
+func (h *CommandHandler) handleAdminPatient(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermViewBookings) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	query := patientQuery(args)
+	if query == "" {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Использование: /admin_patient ID|телефон\n"+
+			"ID — Telegram ID пациента, например из уведомления о записи."))
+		return
+	}
+
+	card, err := h.patientService.GetPatientCard(query)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Пациент не найден."))
+		} else {
+			reportError(ctx, "patient_card", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения карточки пациента."))
+		}
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatPatientCard(card, h.now()))
+	msg.ReplyMarkup = patientCardKeyboard(card)
+	h.bot.Send(msg)
+}
+
+// patientQuery оставляет от Telegram ID или телефона только цифры; пусто, если
+// это не номер
+func patientQuery(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.Trim(s, "0123456789 -()+.") != "" {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// patientKey — номер, по которому карточка находится снова: Telegram ID, если
+// пациент записывался через бота, иначе телефон
+func patientKey(card *model.PatientCard) string {
+	if card.Patient != nil {
+		return strconv.FormatInt(card.Patient.UserID, 10)
+	}
+	return patientQuery(card.Phone)
+}
+
+// formatPatientCard — профиль пациента, сводка и приёмы: предстоящие и последние
+// прошедшие, заметки врачей
+func formatPatientCard(card *model.PatientCard, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString("👤 " + card.Name + "\nТелефон: " + card.Phone + "\n")
+	if p := card.Patient; p != nil {
+		sb.WriteString("Telegram ID: " + strconv.FormatInt(p.UserID, 10) + "\n")
+		if p.BirthDate != "" {
+			sb.WriteString("Дата рождения: " + p.BirthDate + "\n")
+		}
+		if p.Preferences != "" {
+			sb.WriteString("Пожелания: " + p.Preferences + "\n")
+		}
+	} else {
+		sb.WriteString("Через бота сам не записывался\n")
+	}
+	sb.WriteString("\nЗаявок: " + strconv.Itoa(len(card.Bookings)) +
+		" · отмен: " + strconv.Itoa(card.CountStatus(model.StatusCancelled)) +
+		" · неявок: " + strconv.Itoa(card.CountStatus(model.StatusNoShow)) + "\n")
+
+	// Заявки идут от поздних к ранним; предстоящие показываем по порядку
+	current := now.Format("2006-01-02 15:04")
+	var upcoming, past []*model.Booking
+	for _, b := range card.Bookings {
+		if b.DateTime >= current {
+			upcoming = append([]*model.Booking{b}, upcoming...)
+		} else {
+			past = append(past, b)
+		}
+	}
+
+	if len(upcoming) > 0 {
+		sb.WriteString("\nПредстоящие:\n")
+		for _, b := range upcoming {
+			sb.WriteString(patientBookingLine(b) + "\n")
+		}
+	}
+	if len(past) > 0 {
+		sb.WriteString("\nПрошедшие:\n")
+		for i, b := range past {
+			if i == patientHistoryLimit {
+				sb.WriteString("…и ещё " + strconv.Itoa(len(past)-i) + "\n")
+				break
+			}
+			sb.WriteString(patientBookingLine(b) + "\n")
+		}
+	}
+
+	var notes []string
+	for _, b := range card.Bookings {
+		if b.VisitNote != "" {
+			note := b.DateTime
+			if b.Doctor != "" {
+				note += ", " + b.Doctor
+			}
+			notes = append(notes, note+": "+b.VisitNote)
+		}
+	}
+	if len(notes) > 0 {
+		sb.WriteString("\nЗаметки врачей:\n" + strings.Join(notes, "\n") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// patientBookingLine — заявка в карточке пациента, без его имени и телефона
+func patientBookingLine(b *model.Booking) string {
+	line := "#" + strconv.Itoa(b.ID) + " · " + b.DateTime + " · " + model.StatusTitle(b.Status) + " · " + b.Service
+	if b.Doctor != "" {
+		line += ", врач: " + b.Doctor
+	}
+	return line
+}
+
+// patientCardKeyboard — написать пациенту через бота (если он писал боту) и записать его
+func patientCardKeyboard(card *model.PatientCard) tgbotapi.InlineKeyboardMarkup {
+	prefix := "patient:" + patientKey(card) + ":"
+	var row []tgbotapi.InlineKeyboardButton
+	if card.Patient != nil {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("✉️ Написать", prefix+patientMessage))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData("➕ Записать", prefix+patientBook))
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// handlePatientCallback обрабатывает кнопки карточки пациента
+func (h *CallbackHandler) handlePatientCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	if !h.access.Can(callback.Message.Chat.ID, callback.From.ID, model.PermManageBookings) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "У вас нет прав для этой операции."))
+		return
+	}
+
+	key, action, _ := strings.Cut(strings.TrimPrefix(data, "patient:"), ":")
+	card, err := h.patientService.GetPatientCard(key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Пациент не найден."))
+		} else {
+			reportError(ctx, "patient_card", err)
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка получения карточки пациента."))
+		}
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	session := &model.Booking{AdminID: callback.From.ID}
+	var prompt string
+	var keyboard interface{}
+	switch {
+	case action == patientMessage && card.Patient != nil:
+		session.EditField = model.EditMessage
+		session.PatientID = card.Patient.UserID
+		prompt = "Сообщение пациенту " + card.Name + ". Бот отправит его от имени клиники:"
+	case action == patientBook:
+		// Имя и телефон уже известны — запись начинается с выбора услуги
+		session.Step = 3
+		session.Name, session.Phone = card.Name, card.Phone
+		if card.Patient != nil {
+			session.PatientID = card.Patient.UserID
+		}
+		prompt = "Запись пациента " + card.Name + ", " + card.Phone + ".\nУслуга:"
+		keyboard = ui.ServiceKeyboard()
+	default:
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Неизвестное действие."))
+		return
+	}
+
+	h.userBookings[callbackKey(callback)] = session
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	msg := tgbotapi.NewMessage(chatID, prompt)
+	if keyboard != nil {
+		msg.ReplyMarkup = keyboard
+	}
+	h.bot.Send(msg)
+}
+
+// sendPatientMessage отправляет пациенту сообщение администратора от имени клиники.
+// Что пишет тот, кто нажал «Написать», и права у него ещё есть, проверил
+// Sessions.lookup, когда нашёл сессию.
+func (h *CommandHandler) sendPatientMessage(ctx context.Context, key SessionKey, session *model.Booking, text string) {
+	chatID := key.ChatID
+	delete(h.userBookings, key)
+
+	_, err := h.bot.Send(tgbotapi.NewMessage(session.PatientID, "✉️ Сообщение из клиники:\n\n"+text))
+	if err != nil {
+		reply := "Ошибка отправки сообщения."
+		if telegram.Permanent(err) {
+			reply = "Сообщение не доставлено: пациент остановил бота."
+		} else {
+			reportError(ctx, "patient_message", err)
+		}
+		h.bot.Send(tgbotapi.NewMessage(chatID, reply))
+		return
+	}
+
+	done := tgbotapi.NewMessage(chatID, "✅ Сообщение отправлено пациенту.")
+	done.ReplyMarkup = ui.AdminMenuKeyboard()
+	h.bot.Send(done)
+}
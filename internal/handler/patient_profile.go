@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// stepKnownPatient — шаг записи, на котором пациент подтверждает сохранённые
+	// имя и телефон; после него запись продолжается с выбора услуги (шаг 3)
+	stepKnownPatient = 10
+
+	knownPatientYes    = "Да"
+	knownPatientChange = "Изменить"
+
+	// preferencesLimit — предел длины пожеланий пациента в символах
+	preferencesLimit = 500
+)
+
+const profileUsage = "Изменить профиль:\n" +
+	"/profile birth YYYY-MM-DD — дата рождения\n" +
+	"/profile wishes текст — пожелания: удобное время, врач и т.п.\n" +
+	"Имя и телефон обновляются при записи на приём."
+
+// handleProfile показывает пациенту его профиль и меняет дату рождения и пожелания
+func (h *CommandHandler) handleProfile(ctx context.Context, chatID int64, userID int64, args string) {
+	if chatID != userID {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Профиль доступен в личном чате с ботом."))
+		return
+	}
+
+	field, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	value = strings.TrimSpace(value)
+
+	var patient *model.Patient
+	var err error
+	switch field {
+	case "":
+		patient, err = h.patientService.GetPatient(userID)
+	case "birth":
+		patient, err = h.patientService.SetBirthDate(userID, value)
+	case "wishes":
+		if utf8.RuneCountInString(value) > preferencesLimit {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Слишком длинный текст пожеланий."))
+			return
+		}
+		patient, err = h.patientService.SetPreferences(userID, value)
+	default:
+		h.bot.Send(tgbotapi.NewMessage(chatID, profileUsage))
+		return
+	}
+	if err != nil {
+		text := "Ошибка получения профиля."
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			text = "Профиль появится после первой записи на приём."
+		case errors.Is(err, service.ErrInvalidBirthDate):
+			text = "Неверная дата рождения. Используйте формат YYYY-MM-DD."
+		default:
+			reportError(ctx, "patient_profile", err)
+		}
+		h.bot.Send(tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Ваш профиль:\n\n"+formatPatient(patient)+"\n\n"+profileUsage))
+}
+
+// formatPatient — данные профиля пациента
+func formatPatient(p *model.Patient) string {
+	text := "Имя: " + p.Name + "\n" +
+		"Телефон: " + p.Phone
+	if p.BirthDate != "" {
+		text += "\nДата рождения: " + p.BirthDate
+	}
+	if p.Preferences != "" {
+		text += "\nПожелания: " + p.Preferences
+	}
+	return text
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+// Sender — часть Bot API, через которую обработчики отвечают пользователям.
+// В работе это telegram.Client, в тестах и при проигрывании записей — replay.Capture.
+type Sender = telegram.Sender
+
+// WithSender возвращает копию обработчика, которая отправляет через bot. Через
+// неё фоновые рассылки идут клиентом с повторами, а не клиентом ответов на
+// обновления.
+func (h *CommandHandler) WithSender(bot Sender) *CommandHandler {
+	c := *h
+	c.bot = bot
+	return &c
+}
+
+// WithSender — то же для CallbackHandler
+func (h *CallbackHandler) WithSender(bot Sender) *CallbackHandler {
+	c := *h
+	c.bot = bot
+	return &c
+}
+
+// WithClock возвращает копию обработчика, для которой сейчас — now(). Тесты и
+// проигрывание записей так отвечают на тот момент, который им нужен.
+func (h *CommandHandler) WithClock(now func() time.Time) *CommandHandler {
+	c := *h
+	c.now = now
+	return &c
+}
+
+// WithClock — то же для CallbackHandler
+func (h *CallbackHandler) WithClock(now func() time.Time) *CallbackHandler {
+	c := *h
+	c.now = now
+	return &c
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+// SessionKey — чат и пользователь незавершённого сценария: записи, изменения
+// заявки, заметки или сообщения пациенту. В личном чате пользователь один, а в
+// группе у каждого участника своя сессия, и чужие сообщения в неё не попадают.
+type SessionKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// Sessions — незавершённые сценарии; общие для CommandHandler и CallbackHandler
+type Sessions map[SessionKey]*model.Booking
+
+func messageKey(msg *tgbotapi.Message) SessionKey {
+	return SessionKey{ChatID: msg.Chat.ID, UserID: msg.From.ID}
+}
+
+func callbackKey(callback *tgbotapi.CallbackQuery) SessionKey {
+	return SessionKey{ChatID: callback.Message.Chat.ID, UserID: callback.From.ID}
+}
+
+// lookup возвращает сессию пользователя. Сессия сотрудника продолжается, только
+// пока у него есть права на её действие; иначе она удаляется.
+func (s Sessions) lookup(access *service.AccessService, key SessionKey) (*model.Booking, bool) {
+	session, ok := s[key]
+	if !ok || session.AdminID == 0 {
+		return session, ok
+	}
+
+	// Заметку о приёме пишет врач, остальное — работа с заявками
+	perm := model.PermManageBookings
+	if session.EditField == model.EditNote {
+		perm = model.PermOwnSchedule
+	}
+	if session.AdminID != key.UserID || !access.Can(key.ChatID, key.UserID, perm) {
+		delete(s, key)
+		return nil, false
+	}
+	return session, true
+}
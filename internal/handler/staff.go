@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+const grantUsage = "Использование: /admin_grant ID роль [имя], например /admin_grant 123456789 receptionist Анна\n\n" +
+	"Роли: owner — владелец, admin — администратор, receptionist — регистратор, doctor — врач.\n" +
+	"Для роли doctor укажите имя врача из /admin_doctors: /admin_grant 123456789 doctor Анна Петрова"
+
+// handleAdminStaff показывает владельцев из настроек и сотрудников с ролями
+func (h *CommandHandler) handleAdminStaff(ctx context.Context, chatID int64, userID int64) {
+	if !h.access.Can(chatID, userID, model.PermManageStaff) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	staff, err := h.access.GetStaff()
+	if err != nil {
+		reportError(ctx, "admin_staff", err)
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка получения списка сотрудников."))
+		return
+	}
+
+	text := "👥 Сотрудники:\n"
+	for _, id := range h.access.Owners() {
+		text += "\n" + strconv.FormatInt(id, 10) + " — " + model.RoleTitle(model.RoleOwner) + " (ADMIN_USER_IDS)"
+	}
+	for _, s := range staff {
+		text += "\n" + strconv.FormatInt(s.UserID, 10) + " — " + model.RoleTitle(s.Role)
+		if s.Name != "" {
+			text += ", " + s.Name
+		}
+	}
+	h.bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// handleAdminGrant выдаёт роль: "/admin_grant ID роль [имя]"
+func (h *CommandHandler) handleAdminGrant(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermManageStaff) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		h.bot.Send(tgbotapi.NewMessage(chatID, grantUsage))
+		return
+	}
+	targetID, err := strconv.ParseInt(fields[0], 10, 64)
+	role := strings.ToLower(fields[1])
+	if err != nil || !model.IsValidRole(role) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, grantUsage))
+		return
+	}
+
+	staff := &model.Staff{UserID: targetID, Role: role, Name: strings.Join(fields[2:], " "), GrantedBy: userID}
+	if role == model.RoleDoctor {
+		// Врач видит только свои приёмы, поэтому роль привязывается к записи врача
+		if staff.Name == "" {
+			h.bot.Send(tgbotapi.NewMessage(chatID, grantUsage))
+			return
+		}
+		doctor, err := h.doctorService.GetDoctorByName(staff.Name)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Врач «"+staff.Name+"» не найден. Добавьте его: /admin_doctor_add "+staff.Name))
+			} else {
+				reportError(ctx, "admin_grant", err)
+				h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка выдачи роли."))
+			}
+			return
+		}
+		staff.DoctorID, staff.Name = doctor.ID, doctor.Name
+	}
+
+	if err := h.access.Grant(staff); err != nil {
+		if errors.Is(err, service.ErrBootstrapOwner) {
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Пользователь "+fields[0]+" — владелец из ADMIN_USER_IDS, его роль меняется только в настройках."))
+		} else {
+			reportError(ctx, "admin_grant", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка выдачи роли."))
+		}
+		return
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, "✅ Пользователю "+fields[0]+" выдана роль «"+model.RoleTitle(role)+"»."))
+}
+
+// handleAdminRevoke отзывает роль: "/admin_revoke ID"
+func (h *CommandHandler) handleAdminRevoke(ctx context.Context, chatID int64, userID int64, args string) {
+	if !h.access.Can(chatID, userID, model.PermManageStaff) {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для этой команды."))
+		return
+	}
+
+	targetID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		h.bot.Send(tgbotapi.NewMessage(chatID, "Укажите ID пользователя: /admin_revoke 123456789"))
+		return
+	}
+
+	if err := h.access.Revoke(targetID, userID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrBootstrapOwner):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Этот владелец задан в ADMIN_USER_IDS, его роль меняется только в настройках."))
+		case errors.Is(err, sql.ErrNoRows):
+			h.bot.Send(tgbotapi.NewMessage(chatID, "У пользователя нет роли."))
+		default:
+			reportError(ctx, "admin_revoke", err)
+			h.bot.Send(tgbotapi.NewMessage(chatID, "Ошибка отзыва роли."))
+		}
+		return
+	}
+
+	h.bot.Send(tgbotapi.NewMessage(chatID, "Роль пользователя "+strconv.FormatInt(targetID, 10)+" отозвана."))
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// nearestDatesCount — сколько ближайших свободных дат предлагать вместо занятого дня
+const nearestDatesCount = 3
+
+// timeSlotsMessage готовит выбор свободного времени на дату date для сессии session.
+// ok равно false, если выбирать нечего: тогда сообщение объясняет причину и, когда
+// день занят, предлагает ближайшие свободные даты.
+func timeSlotsMessage(ctx context.Context, bookings *service.BookingService, chatID int64, date string, session *model.Booking, now time.Time) (tgbotapi.MessageConfig, bool) {
+	// Validate date format
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return tgbotapi.NewMessage(chatID, "Неверный формат даты. Пожалуйста, используйте формат YYYY-MM-DD"), false
+	}
+
+	// Get available time slots for the selected date
+	// Прошедшее время, в том числе сегодняшнее, не предлагается
+	slots, err := bookings.GetAvailableTimeSlots(date, now)
+	if err != nil {
+		reportError(ctx, "time_slots", err)
+		return tgbotapi.NewMessage(chatID, "Ошибка при получении доступного времени. Пожалуйста, попробуйте другую дату."), false
+	}
+	if len(slots) == 0 {
+		return noSlotsMessage(ctx, bookings, chatID, date, session, now), false
+	}
+
+	// Create keyboard with available time slots
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, slot := range slots {
+		timeStr := slot[11:16] // Extract time part (HH:MM)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(timeStr, "time:"+slot),
+		})
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Выберите удобное время:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+	return msg, true
+}
+
+// noSlotsMessage сообщает, что на дату нет времени, и предлагает кнопками ближайшие
+// свободные даты, а пациенту — ещё и лист ожидания
+func noSlotsMessage(ctx context.Context, bookings *service.BookingService, chatID int64, date string, session *model.Booking, now time.Time) tgbotapi.MessageConfig {
+	var sb strings.Builder
+	var rows [][]tgbotapi.InlineKeyboardButton
+	sb.WriteString("На выбранную дату нет доступного времени.")
+
+	dates, err := bookings.NearestAvailableDates(date, nearestDatesCount, now)
+	if err != nil {
+		// Без подсказки пользователь всё равно может ввести другую дату
+		reportError(ctx, "nearest_dates", err)
+	}
+	if len(dates) > 0 {
+		sb.WriteString(" Ближайшие свободные даты — выберите кнопкой или введите другую дату.")
+		var row []tgbotapi.InlineKeyboardButton
+		for _, d := range dates {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(dateButtonTitle(d), "date:"+d))
+		}
+		rows = append(rows, row)
+	} else {
+		sb.WriteString(" Пожалуйста, выберите другую дату.")
+	}
+
+	// Пациент может подождать, пока время освободится
+	if session != nil && session.AdminID == 0 && session.PatientID != 0 && session.EditField == "" {
+		sb.WriteString("\nИли встаньте в лист ожидания — бот напишет, когда время освободится.")
+		rows = append(rows, waitlistKeyboard(date).InlineKeyboard...)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, sb.String())
+	if len(rows) > 0 {
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	}
+	return msg
+}
+
+// dateButtonTitle — подпись кнопки даты: день недели и число, например «Пн 05.03»
+func dateButtonTitle(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return weekdayShort[t.Weekday()] + " " + t.Format("02.01")
+}
+
+// handleDateCallback показывает свободное время на дату, выбранную кнопкой из подсказки
+func (h *CallbackHandler) handleDateCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	chatID := callback.Message.Chat.ID
+	date := strings.TrimPrefix(data, "date:")
+
+	// Дату выбирают при новой записи (шаг 4) или при переносе существующей
+	session, exists := h.userBookings.lookup(h.access, callbackKey(callback))
+	if !exists || (session.Step != 4 && session.EditField != model.EditTime) {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка: сессия бронирования не найдена."))
+		return
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	msg, ok := timeSlotsMessage(ctx, h.bookingService, chatID, date, session, h.now())
+	h.bot.Send(msg)
+	if ok && session.EditField == "" {
+		session.Step++
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+	"github.com/REmakerzz/dental-clinic-bot/internal/ui"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// waitlistWeekDays — сколько дней, начиная с выбранного, ждёт кнопка «Ждать неделю»
+const waitlistWeekDays = 7
+
+// Действия кнопок предложения освободившегося времени: "offer:ID:действие"
+const (
+	offerClaim   = "claim"
+	offerDecline = "decline"
+)
+
+// waitlistKeyboard предлагает встать в лист ожидания на день date или неделю с него
+func waitlistKeyboard(date string) tgbotapi.InlineKeyboardMarkup {
+	weekEnd := date
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		weekEnd = t.AddDate(0, 0, waitlistWeekDays-1).Format("2006-01-02")
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🕒 Ждать этот день", "wait:"+date+":"+date),
+		tgbotapi.NewInlineKeyboardButtonData("📆 Ждать неделю", "wait:"+date+":"+weekEnd),
+	))
+}
+
+// dayPartKeyboard — выбор удобного времени суток для листа ожидания
+func dayPartKeyboard(from, to string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, part := range model.DayParts {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(model.DayPartTitle(part), "wait:"+from+":"+to+":"+part),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleWaitlistCallback обрабатывает "wait:с:по" (выбрать время суток) и
+// "wait:с:по:время_суток" (встать в лист ожидания)
+func (h *CallbackHandler) handleWaitlistCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	chatID := callback.Message.Chat.ID
+	parts := strings.Split(strings.TrimPrefix(data, "wait:"), ":")
+	if len(parts) < 2 {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректный запрос."))
+		return
+	}
+	from, to := parts[0], parts[1]
+
+	// Лист ожидания — для пациентов, которые записываются сами: предложения
+	// приходят им в личный чат
+	session, exists := h.userBookings.lookup(h.access, callbackKey(callback))
+	if !exists || session.AdminID != 0 || session.PatientID == 0 || session.Service == "" {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка: сессия бронирования не найдена."))
+		return
+	}
+
+	if len(parts) == 2 {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		h.bot.Request(tgbotapi.NewEditMessageTextAndMarkup(chatID, callback.Message.MessageID,
+			"Какое время вам удобно?", dayPartKeyboard(from, to)))
+		return
+	}
+
+	entry := &model.WaitlistEntry{
+		UserID:   session.PatientID,
+		Name:     session.Name,
+		Phone:    session.Phone,
+		Service:  session.Service,
+		DateFrom: from,
+		DateTo:   to,
+		DayPart:  parts[2],
+	}
+	if err := h.waitlistService.Join(entry, h.now()); err != nil {
+		text := "Ошибка записи в лист ожидания."
+		switch {
+		case errors.Is(err, service.ErrWaitlistFull):
+			text = "Вы уже ждёте время на несколько дат. Дождитесь предложения или выберите другую дату."
+		case errors.Is(err, service.ErrInvalidWaitRange):
+			text = "Эти даты уже прошли, выберите другую дату."
+		default:
+			reportError(ctx, "waitlist_join", err)
+		}
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, text))
+		return
+	}
+
+	delete(h.userBookings, callbackKey(callback))
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	h.bot.Request(tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID))
+
+	dates := from
+	if to != from {
+		dates = from + " — " + to
+	}
+	msg := tgbotapi.NewMessage(chatID, "🕒 Вы в листе ожидания: "+dates+", "+strings.ToLower(model.DayPartTitle(entry.DayPart))+".\n"+
+		"Когда время освободится, бот предложит его вам, подтвердить нужно будет в течение "+
+		formatMinutes(h.waitlistService.ClaimTTL())+".")
+	msg.ReplyMarkup = ui.MainMenuKeyboard()
+	h.bot.Send(msg)
+}
+
+// OfferFreedSlots предлагает освободившееся время пациентам из листа ожидания
+func (h *CallbackHandler) OfferFreedSlots(ctx context.Context, now time.Time) error {
+	offers, err := h.waitlistService.OfferFreedSlots(now)
+	for _, offer := range offers {
+		id := strconv.FormatInt(offer.ID, 10)
+		msg := tgbotapi.NewMessage(offer.Entry.UserID, "🔔 Освободилось время: "+offer.DateTime+"\n"+
+			"Услуга: "+offer.Entry.Service+"\n\n"+
+			"Записаться? Предложение действует "+formatMinutes(h.waitlistService.ClaimTTL())+
+			", потом время предложат следующему пациенту.")
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Записаться", "offer:"+id+":"+offerClaim),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Не подходит", "offer:"+id+":"+offerDecline),
+		))
+		if _, sendErr := h.bot.Send(msg); sendErr != nil {
+			// Пациент не узнал о предложении — время не придерживается и уходит следующему
+			if err := h.waitlistService.Withdraw(offer.ID, now); err != nil {
+				reportError(ctx, "waitlist_withdraw", err)
+			}
+		}
+	}
+	return err
+}
+
+// handleOfferCallback обрабатывает ответ пациента на предложенное время
+func (h *CallbackHandler) handleOfferCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data string) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(data, "offer:"), ":")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Некорректное предложение."))
+		return
+	}
+
+	chatID := callback.Message.Chat.ID
+	var booking *model.Booking
+	var text string
+	switch action {
+	case offerClaim:
+		booking, err = h.waitlistService.Claim(id, callback.From.ID, h.now(), func(b *model.Booking) *model.Notification {
+			return newBookingNotification(h.config.AdminGroupChatID, b, "")
+		})
+		if err == nil {
+			text = "✅ Вы записаны на " + booking.DateTime + ". Заявка #" + strconv.Itoa(booking.ID) + " сохранена."
+		}
+	case offerDecline:
+		err = h.waitlistService.Decline(id, callback.From.ID, h.now())
+		text = "Хорошо, предложим другое время, если оно освободится."
+	default:
+		h.bot.Request(tgbotapi.NewCallback(callback.ID, "Неизвестное действие."))
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Предложение не найдено."))
+			return
+		case errors.Is(err, service.ErrOfferClosed):
+			text = "⌛ Предложение уже неактуально: время истекло."
+		case errors.Is(err, repository.ErrSlotUnavailable):
+			text = "Увы, это время уже заняли. Вы остаётесь в листе ожидания."
+		default:
+			reportError(ctx, "waitlist_"+action, err)
+			h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка, попробуйте ещё раз."))
+			return
+		}
+	}
+
+	h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	h.bot.Request(tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, text))
+	if booking != nil {
+		h.sendCalendarFile(ctx, chatID, booking)
+	}
+}
+
+// formatMinutes — длительность в минутах для сообщений пациенту
+func formatMinutes(d time.Duration) string {
+	return strconv.Itoa(int(d.Minutes())) + " мин"
+}
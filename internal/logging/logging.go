@@ -0,0 +1,77 @@
+// Package logging настраивает структурированный лог (log/slog) и маскирует в нём
+// персональные данные пациентов
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Форматы вывода лога
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New создаёт логгер с указанным уровнем (debug, info, warn, error) и форматом.
+// Значения атрибутов с именами из sensitiveKeys и номера телефонов в любых
+// строках, включая текст сообщения, маскируются автоматически.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+	switch format {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+}
+
+type ctxKey struct{}
+
+// WithLogger сохраняет в контексте логгер с полями текущего обновления
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext возвращает логгер из контекста или логгер по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// sensitiveKeys — атрибуты, значения которых всегда маскируются
+var sensitiveKeys = map[string]func(string) string{
+	"name":    MaskName,
+	"patient": MaskName,
+	"phone":   MaskPhone,
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindString && a.Value.Kind() != slog.KindAny {
+		return a
+	}
+
+	if mask, ok := sensitiveKeys[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, mask(a.Value.String()))
+	}
+
+	switch v := a.Value.Any().(type) {
+	case string:
+		return slog.String(a.Key, MaskPhones(v))
+	case error:
+		return slog.String(a.Key, MaskPhones(v.Error()))
+	}
+	return a
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// phoneVisibleDigits — сколько последних цифр телефона остаётся в логе
+	phoneVisibleDigits = 2
+	// Номер телефона в свободном тексте: от 10 до 15 цифр
+	phoneMinDigits = 10
+	phoneMaxDigits = 15
+)
+
+var (
+	phoneCandidate = regexp.MustCompile(`\+?\d[\d\s().-]{8,}\d`)
+	dateLike       = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// MaskPhone оставляет от номера только последние цифры: "+7 999 123-45-67" → "***67"
+func MaskPhone(phone string) string {
+	digits := make([]rune, 0, len(phone))
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) <= phoneVisibleDigits {
+		return "***"
+	}
+	return "***" + string(digits[len(digits)-phoneVisibleDigits:])
+}
+
+// MaskName оставляет первую букву каждого слова: "Иван Петров" → "И*** П***"
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		r, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(r) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// MaskPhones маскирует похожие на телефон последовательности цифр в тексте.
+// Даты вида 2006-01-02 15:04 не считаются телефонами.
+func MaskPhones(s string) string {
+	return phoneCandidate.ReplaceAllStringFunc(s, func(m string) string {
+		if dateLike.MatchString(m) {
+			return m
+		}
+		n := 0
+		for _, r := range m {
+			if unicode.IsDigit(r) {
+				n++
+			}
+		}
+		if n < phoneMinDigits || n > phoneMaxDigits {
+			return m
+		}
+		return MaskPhone(m)
+	})
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name string
+		mask func(string) string
+		in   string
+		want string
+	}{
+		{"phone", MaskPhone, "+7 999 123-45-67", "***67"},
+		{"phone without digits", MaskPhone, "нет", "***"},
+		{"phone too short", MaskPhone, "12", "***"},
+		{"name", MaskName, "Иван Петров", "И*** П***"},
+		{"name with extra spaces", MaskName, "  анна  ", "а***"},
+		{"empty name", MaskName, "", ""},
+		{"phone in text", MaskPhones, "перезвонить +7 (999) 123-45-67 завтра", "перезвонить ***67 завтра"},
+		{"several phones", MaskPhones, "89991234567, 8 999 765 43 21", "***67, ***21"},
+		{"date is not a phone", MaskPhones, "запись на 2030-01-07 10:00", "запись на 2030-01-07 10:00"},
+		{"short number", MaskPhones, "заявка 123456789", "заявка 123456789"},
+		{"too many digits", MaskPhones, "счёт 1234567890123456", "счёт 1234567890123456"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mask(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRedactAttr проверяет маскировку через ReplaceAttr, в том числе полей
+// заявки, которая попадает в лог вложенной группой booking
+func TestRedactAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	booking := &model.Booking{ID: 7, Name: "Иван Петров", Phone: "+7 999 123-45-67", Service: "Чистка", DateTime: "2030-01-07 10:00"}
+	logger.Info("звонок на +7 999 765-43-21",
+		"booking", booking,
+		"patient", "Анна Сидорова",
+		"error", errors.New("not delivered to 89991112233"),
+		"count", 89991112233,
+	)
+
+	var entry struct {
+		Msg     string `json:"msg"`
+		Patient string `json:"patient"`
+		Error   string `json:"error"`
+		Count   int64  `json:"count"`
+		Booking struct {
+			ID       int    `json:"id"`
+			Name     string `json:"name"`
+			Phone    string `json:"phone"`
+			DateTime string `json:"datetime"`
+		} `json:"booking"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+
+	checks := []struct{ field, got, want string }{
+		{"msg", entry.Msg, "звонок на ***21"},
+		{"patient", entry.Patient, "А*** С***"},
+		{"error", entry.Error, "not delivered to ***33"},
+		{"booking.name", entry.Booking.Name, "И*** П***"},
+		{"booking.phone", entry.Booking.Phone, "***67"},
+		{"booking.datetime", entry.Booking.DateTime, "2030-01-07 10:00"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	// Числа не маскируются
+	if entry.Booking.ID != 7 || entry.Count != 89991112233 {
+		t.Errorf("numbers changed: %s", buf.String())
+	}
+}
@@ -0,0 +1,141 @@
+// Package metrics — метрики Prometheus бота
+package metrics
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "dental_bot"
+
+// Registry содержит метрики бота и стандартные метрики процесса и Go runtime
+var Registry = prometheus.NewRegistry()
+
+var (
+	// UpdatesHandled считает обработанные обновления по типу: command, message, callback, other
+	UpdatesHandled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_handled_total",
+		Help:      "Telegram updates handled, by type.",
+	}, []string{"type"})
+
+	// BookingsCreated считает созданные заявки по источнику: patient, admin или waitlist
+	BookingsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_created_total",
+		Help:      "Bookings created, by source.",
+	}, []string{"source"})
+
+	// BookingsCancelled считает отменённые и удалённые заявки
+	BookingsCancelled = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookings_cancelled_total",
+		Help:      "Bookings cancelled or deleted.",
+	})
+
+	// WaitlistOffers считает предложения освободившегося времени по исходу:
+	// offered, claimed, declined или expired
+	WaitlistOffers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "waitlist_offers_total",
+		Help:      "Freed slots offered to waitlisted patients, by outcome.",
+	}, []string{"result"})
+
+	// HandlerErrors считает ошибки обработчиков по операции
+	HandlerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "handler_errors_total",
+		Help:      "Errors while handling updates, by operation.",
+	}, []string{"operation"})
+
+	// TelegramRequestDuration — длительность запросов к Bot API по методу
+	TelegramRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_request_duration_seconds",
+		Help:      "Telegram Bot API request latency, by method.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "result"}) // result: ok, error или HTTP-код ответа
+
+	// TelegramSendRetries считает повторы запросов к Bot API по методу
+	TelegramSendRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_retries_total",
+		Help:      "Telegram Bot API requests retried after a transient failure, by method.",
+	}, []string{"method"})
+
+	// TelegramSendFailures считает запросы к Bot API, которые не удалось выполнить
+	TelegramSendFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_failures_total",
+		Help:      "Telegram Bot API requests that failed permanently or ran out of retries, by method.",
+	}, []string{"method"})
+
+	// OutboxDeadLetters считает уведомления, которые outbox так и не смог доставить
+	OutboxDeadLetters = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_dead_letters_total",
+		Help:      "Staff notifications given up on after permanent errors or too many attempts.",
+	})
+
+	// ActiveBookingSessions — сколько чатов сейчас в середине диалога записи
+	ActiveBookingSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_booking_sessions",
+		Help:      "Chats with an unfinished booking dialogue.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		UpdatesHandled,
+		BookingsCreated,
+		BookingsCancelled,
+		WaitlistOffers,
+		HandlerErrors,
+		TelegramRequestDuration,
+		TelegramSendRetries,
+		TelegramSendFailures,
+		OutboxDeadLetters,
+		ActiveBookingSessions,
+	)
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentTransport измеряет длительность запросов к Bot API. Метод берётся из
+// последнего сегмента пути (/bot<token>/sendMessage), поэтому токен в метки не попадает.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+
+		result := "ok"
+		if err != nil {
+			result = "error"
+		} else if resp.StatusCode >= 400 {
+			result = strconv.Itoa(resp.StatusCode)
+		}
+		TelegramRequestDuration.WithLabelValues(path.Base(req.URL.Path), result).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
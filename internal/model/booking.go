@@ -1,10 +1,137 @@
 package model
 
+import (
+    "log/slog"
+    "time"
+)
+
+// Статусы заявки
+const (
+    StatusNew       = "new"
+    StatusConfirmed = "confirmed"
+    StatusCompleted = "completed"
+    StatusCancelled = "cancelled"
+    StatusNoShow    = "no_show"
+)
+
+// Statuses перечисляет все статусы в порядке жизненного цикла заявки
+var Statuses = []string{StatusNew, StatusConfirmed, StatusCompleted, StatusCancelled, StatusNoShow}
+
+var statusTitles = map[string]string{
+    StatusNew:       "Новая",
+    StatusConfirmed: "Подтверждена",
+    StatusCompleted: "Завершена",
+    StatusCancelled: "Отменена",
+    StatusNoShow:    "Неявка",
+}
+
+// StatusTitle возвращает название статуса для показа пользователю
+func StatusTitle(status string) string {
+    if title, ok := statusTitles[status]; ok {
+        return title
+    }
+    return status
+}
+
+// IsValidStatus сообщает, известен ли статус
+func IsValidStatus(status string) bool {
+    _, ok := statusTitles[status]
+    return ok
+}
+
 type Booking struct {
     ID         int
     Name       string
     Phone      string
     Service    string
     DateTime   string
+    Status     string
+    DoctorID   int64
+    Doctor     string // имя врача, если назначен
+    CreatedAt  string
+    UpdatedAt  string
+    Sequence   int // номер версии для календарей, растёт при каждом изменении
+    VisitNote  string // заметка врача о приёме
+    PatientID  int64 // Telegram ID пациента, если он записался сам
     Step       int // номер шага сценария
+
+    // Поля сессии администратора: кто оформляет запись за пациента и какое
+    // поле существующей заявки (ID) редактируется
+    AdminID    int64
+    EditField  string
+}
+
+// LogValue описывает заявку в логе. Имя и телефон передаются под ключами name
+// и phone, которые логгер маскирует.
+func (b *Booking) LogValue() slog.Value {
+    return slog.GroupValue(
+        slog.Int("id", b.ID),
+        slog.String("name", b.Name),
+        slog.String("phone", b.Phone),
+        slog.String("service", b.Service),
+        slog.String("datetime", b.DateTime),
+        slog.String("status", b.Status),
+        slog.Int64("doctor_id", b.DoctorID),
+        slog.Int64("patient_id", b.PatientID),
+    )
+}
+
+// Поля заявки, которые администратор может изменить
+const (
+    EditName    = "name"
+    EditPhone   = "phone"
+    EditService = "service"
+    EditTime    = "time"
+    EditDoctor  = "doctor"
+    EditNote    = "note" // заметка врача о приёме
+    EditMessage = "message" // сообщение пациенту от администратора
+)
+
+// BookingFilter задаёт отбор заявок; пустые поля не ограничивают выборку
+type BookingFilter struct {
+    DateFrom string // YYYY-MM-DD включительно
+    DateTo   string // YYYY-MM-DD включительно
+    Status   string
+    Service  string // фрагмент названия услуги
+    Doctor   string // фрагмент имени врача
+}
+
+// StatItem — строка разбивки статистики
+type StatItem struct {
+    Name  string
+    Count int
+}
+
+// BookingStats — статистика заявок с приёмом в периоде [From, To]
+type BookingStats struct {
+    From, To       string // YYYY-MM-DD включительно
+    Total          int
+    Cancelled      int
+    NoShow         int
+    Completed      int
+    ByService      []StatItem // по убыванию
+    ByDoctor       []StatItem // по убыванию
+    ByWeekday      [7]int     // индекс — time.Weekday
+    ByHour         [24]int
+    ByDate         []StatItem    // все дни периода по порядку, Name — YYYY-MM-DD
+    Load           [7][24]int    // неотменённые приёмы по дню недели и часу
+    LeadTimeAvg    time.Duration // от создания заявки до приёма
+    LeadTimeMedian time.Duration
+}
+
+// CancellationRate — доля отменённых заявок
+func (s *BookingStats) CancellationRate() float64 {
+    if s.Total == 0 {
+        return 0
+    }
+    return float64(s.Cancelled) / float64(s.Total)
+}
+
+// NoShowRate — доля неявок среди неотменённых заявок
+func (s *BookingStats) NoShowRate() float64 {
+    active := s.Total - s.Cancelled
+    if active == 0 {
+        return 0
+    }
+    return float64(s.NoShow) / float64(active)
 }
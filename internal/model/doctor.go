@@ -0,0 +1,8 @@
+package model
+
+type Doctor struct {
+	ID        int64
+	Name      string
+	FeedToken string // секрет в ссылке на календарь врача
+	IsActive  bool
+}
@@ -0,0 +1,21 @@
+package model
+
+// Статусы уведомления в outbox
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationDead    = "dead" // попытки исчерпаны или ошибка не исправится повтором
+)
+
+// Notification — сообщение сотрудникам, которое доставляется через outbox
+type Notification struct {
+	ID          int64
+	ChatID      int64
+	Text        string
+	ReplyMarkup string // JSON inline-клавиатуры или пусто
+	BookingID   int    // заявка, о которой уведомление, или 0
+	Status      string
+	Attempts    int
+	LastError   string
+	MessageID   int // ID отправленного сообщения
+}
@@ -0,0 +1,42 @@
+package model
+
+import "log/slog"
+
+// Patient — профиль пациента, который записывался через бота; ключ — Telegram ID
+type Patient struct {
+	UserID      int64
+	Name        string
+	Phone       string
+	BirthDate   string // YYYY-MM-DD или пусто
+	Preferences string // пожелания пациента: удобное время, врач и т.п.
+	CreatedAt   string
+	UpdatedAt   string
+}
+
+// LogValue описывает пациента в логе; имя и телефон маскируются логгером
+func (p *Patient) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int64("user_id", p.UserID),
+		slog.String("name", p.Name),
+		slog.String("phone", p.Phone),
+	)
+}
+
+// PatientCard — всё о пациенте для администраторов: профиль и заявки
+type PatientCard struct {
+	Patient  *Patient // nil, если пациент сам через бота не записывался
+	Name     string   // из профиля или последней заявки
+	Phone    string
+	Bookings []*Booking // последние приёмы первыми
+}
+
+// CountStatus — число заявок пациента в статусе status
+func (c *PatientCard) CountStatus(status string) int {
+	n := 0
+	for _, b := range c.Bookings {
+		if b.Status == status {
+			n++
+		}
+	}
+	return n
+}
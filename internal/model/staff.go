@@ -0,0 +1,71 @@
+package model
+
+// Роли сотрудников клиники
+const (
+	RoleOwner        = "owner"
+	RoleAdmin        = "admin"
+	RoleReceptionist = "receptionist"
+	RoleDoctor       = "doctor"
+)
+
+// Roles перечисляет роли от самой широкой к самой узкой
+var Roles = []string{RoleOwner, RoleAdmin, RoleReceptionist, RoleDoctor}
+
+var roleTitles = map[string]string{
+	RoleOwner:        "Владелец",
+	RoleAdmin:        "Администратор",
+	RoleReceptionist: "Регистратор",
+	RoleDoctor:       "Врач",
+}
+
+// RoleTitle возвращает название роли для показа пользователю
+func RoleTitle(role string) string {
+	if title, ok := roleTitles[role]; ok {
+		return title
+	}
+	return role
+}
+
+// IsValidRole сообщает, известна ли роль
+func IsValidRole(role string) bool {
+	_, ok := roleTitles[role]
+	return ok
+}
+
+// Права на группы команд
+const (
+	PermViewSchedule   = "view_schedule"   // расписание на день
+	PermViewBookings   = "view_bookings"   // списки, поиск и карточки заявок
+	PermManageBookings = "manage_bookings" // запись, изменение и удаление заявок
+	PermReports        = "reports"         // статистика, графики и выгрузка
+	PermManageDoctors  = "manage_doctors"
+	PermManageStaff    = "manage_staff" // выдача и отзыв ролей
+	PermOwnSchedule    = "own_schedule" // свои приёмы врача и отметки о них
+)
+
+var rolePermissions = map[string][]string{
+	RoleOwner:        {PermViewSchedule, PermViewBookings, PermManageBookings, PermReports, PermManageDoctors, PermManageStaff},
+	RoleAdmin:        {PermViewSchedule, PermViewBookings, PermManageBookings, PermReports, PermManageDoctors},
+	RoleReceptionist: {PermViewSchedule, PermViewBookings, PermManageBookings},
+	RoleDoctor:       {PermOwnSchedule},
+}
+
+// HasPermission сообщает, даёт ли роль право permission; пустая роль не даёт ничего
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Staff — сотрудник с ролью, выданной через /admin_grant
+type Staff struct {
+	UserID    int64
+	Role      string
+	Name      string
+	DoctorID  int64 // для роли doctor — врач, чьи приёмы видит сотрудник
+	GrantedBy int64
+	CreatedAt string
+}
@@ -0,0 +1,86 @@
+package model
+
+// Время суток, в которое пациенту удобно прийти
+const (
+	DayPartAny       = "any"
+	DayPartMorning   = "morning"   // до 12:00
+	DayPartAfternoon = "afternoon" // 12:00–15:00
+	DayPartEvening   = "evening"   // с 15:00
+)
+
+// DayParts перечисляет время суток в порядке показа пациенту
+var DayParts = []string{DayPartAny, DayPartMorning, DayPartAfternoon, DayPartEvening}
+
+var dayPartTitles = map[string]string{
+	DayPartAny:       "Любое время",
+	DayPartMorning:   "Утро (до 12:00)",
+	DayPartAfternoon: "День (12:00–15:00)",
+	DayPartEvening:   "Вечер (после 15:00)",
+}
+
+// DayPartTitle возвращает название времени суток для показа пользователю
+func DayPartTitle(part string) string {
+	if title, ok := dayPartTitles[part]; ok {
+		return title
+	}
+	return part
+}
+
+// IsValidDayPart сообщает, известно ли время суток
+func IsValidDayPart(part string) bool {
+	_, ok := dayPartTitles[part]
+	return ok
+}
+
+// InDayPart сообщает, попадает ли время приёма clock (HH:MM) во время суток part
+func InDayPart(part, clock string) bool {
+	switch part {
+	case DayPartMorning:
+		return clock < "12:00"
+	case DayPartAfternoon:
+		return clock >= "12:00" && clock < "15:00"
+	case DayPartEvening:
+		return clock >= "15:00"
+	default:
+		return true
+	}
+}
+
+// Статусы записи в листе ожидания
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistBooked  = "booked"  // пациент записался на предложенное время
+	WaitlistExpired = "expired" // даты прошли, а время так и не освободилось
+)
+
+// WaitlistEntry — пациент, который ждёт свободного времени в диапазоне дат
+type WaitlistEntry struct {
+	ID        int64
+	UserID    int64 // Telegram ID пациента, ему уходят предложения
+	Name      string
+	Phone     string
+	Service   string
+	DateFrom  string // YYYY-MM-DD включительно
+	DateTo    string // YYYY-MM-DD включительно
+	DayPart   string
+	Status    string
+	CreatedAt string
+}
+
+// Статусы предложения освободившегося времени
+const (
+	OfferPending  = "pending"
+	OfferClaimed  = "claimed"
+	OfferDeclined = "declined"
+	OfferExpired  = "expired" // пациент не успел ответить или время заняли
+)
+
+// SlotOffer — освободившееся время, предложенное пациенту из листа ожидания
+type SlotOffer struct {
+	ID        int64
+	EntryID   int64
+	DateTime  string // YYYY-MM-DD HH:MM
+	Status    string
+	ExpiresAt string // UTC, YYYY-MM-DD HH:MM:SS
+	Entry     *WaitlistEntry
+}
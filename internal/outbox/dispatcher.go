@@ -0,0 +1,125 @@
+// Package outbox доставляет уведомления сотрудникам, записанные в таблицу outbox
+// вместе с изменениями, о которых они сообщают. Доставка как минимум однократная:
+// уведомление помечается отправленным только после ответа Telegram.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+const (
+	// pollInterval — как часто проверять outbox на новые уведомления
+	pollInterval = 2 * time.Second
+	batchSize    = 20
+
+	// maxAttempts — после стольких неудачных попыток уведомление становится dead
+	maxAttempts  = 10
+	retryBackoff = 30 * time.Second
+	maxBackoff   = time.Hour
+)
+
+// Dispatcher отправляет уведомления из outbox. Временные ошибки повторяются
+// с растущей паузой; постоянные ошибки и исчерпанные попытки переводят
+// уведомление в статус dead и пишутся в лог.
+type Dispatcher struct {
+	db     *sql.DB
+	sender telegram.Sender
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewDispatcher(db *sql.DB, sender telegram.Sender, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{db: db, sender: sender, logger: logger, now: time.Now}
+}
+
+// Run отправляет уведомления, пока не отменён ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchPending(ctx); err != nil {
+			d.logger.Error("failed to read outbox", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// DispatchPending один раз отправляет все уведомления, время которых подошло
+func (d *Dispatcher) DispatchPending(ctx context.Context) error {
+	for {
+		due, err := repository.GetDueNotifications(d.db, d.now(), batchSize)
+		if err != nil {
+			return err
+		}
+		for _, n := range due {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err := d.deliver(n); err != nil {
+				return err
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// deliver отправляет одно уведомление и записывает результат
+func (d *Dispatcher) deliver(n *model.Notification) error {
+	logger := d.logger.With("notification_id", n.ID, "chat_id", n.ChatID, "booking_id", n.BookingID)
+
+	msg, err := d.sender.Send(notificationMessage(n))
+	if err == nil {
+		logger.Debug("notification sent", "attempts", n.Attempts+1)
+		return repository.MarkNotificationSent(d.db, n.ID, msg.MessageID)
+	}
+
+	// Текст ошибки сохраняется в базе, поэтому токен бота из него вырезается
+	err = telegram.RedactToken(err)
+	attempts := n.Attempts + 1
+	dead := attempts >= maxAttempts || telegram.Permanent(err)
+	next := d.now().Add(retryDelay(attempts))
+	if err := repository.MarkNotificationFailed(d.db, n.ID, err.Error(), next, dead); err != nil {
+		return err
+	}
+
+	if dead {
+		logger.Error("notification dead-lettered", "attempts", attempts, "error", err)
+		metrics.OutboxDeadLetters.Inc()
+	} else {
+		logger.Warn("notification not sent, will retry", "attempts", attempts, "retry_at", next, "error", err)
+	}
+	return nil
+}
+
+// notificationMessage собирает сообщение; сохранённая клавиатура прикладывается как есть
+func notificationMessage(n *model.Notification) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(n.ChatID, n.Text)
+	if n.ReplyMarkup != "" {
+		msg.ReplyMarkup = json.RawMessage(n.ReplyMarkup)
+	}
+	return msg
+}
+
+// retryDelay — пауза перед попыткой attempts+1: 30 секунд, удваивается, не больше часа
+func retryDelay(attempts int) time.Duration {
+	return min(retryBackoff<<min(attempts-1, 10), maxBackoff)
+}
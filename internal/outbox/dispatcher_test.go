@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegramtest"
+)
+
+func TestDispatcher(t *testing.T) {
+	serverErr := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	tests := []struct {
+		name       string
+		errs       []error
+		wantStatus string
+		wantTries  int
+	}{
+		{"sent", nil, model.NotificationSent, 1},
+		{"retried until sent", []error{serverErr, errors.New("connection reset")}, model.NotificationSent, 3},
+		{"bot removed from group", []error{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was kicked"}}, model.NotificationDead, 1},
+		{"out of attempts", repeat(serverErr, maxAttempts), model.NotificationDead, maxAttempts},
+		{"network error with token", repeat(&url.Error{Op: "Post", URL: "https://api.telegram.org/bot123456789:AAH-secret_Token/sendMessage",
+			Err: errors.New("connection reset")}, maxAttempts), model.NotificationDead, maxAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := repository.InitDB(repository.InMemory, slog.New(slog.DiscardHandler))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			n := &model.Notification{ChatID: -100, Text: "Новая запись", ReplyMarkup: `{"inline_keyboard":[]}`}
+			if err := repository.EnqueueNotification(db, n); err != nil {
+				t.Fatal(err)
+			}
+
+			sender := &telegramtest.FlakySender{Errs: tt.errs}
+			d := NewDispatcher(db, sender, slog.New(slog.DiscardHandler))
+			now := time.Now()
+			d.now = func() time.Time { return now }
+
+			// Каждый проход — одна попытка; повтор ждёт, пока не наступит его время
+			for range maxAttempts + 1 {
+				if err := d.DispatchPending(t.Context()); err != nil {
+					t.Fatal(err)
+				}
+				now = now.Add(maxBackoff)
+			}
+
+			got, err := repository.GetNotifications(db, tt.wantStatus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || got[0].Attempts != tt.wantTries {
+				t.Fatalf("got %+v, want one %s notification after %d attempts", got, tt.wantStatus, tt.wantTries)
+			}
+			if tt.wantStatus == model.NotificationSent && (len(sender.Sent) != 1 || got[0].MessageID != 42) {
+				t.Errorf("sent %d messages, message_id %d", len(sender.Sent), got[0].MessageID)
+			}
+			if tt.wantStatus == model.NotificationDead && got[0].LastError == "" {
+				t.Error("last error not recorded")
+			}
+			if strings.Contains(got[0].LastError, "AAH-secret_Token") {
+				t.Errorf("bot token saved in last error: %s", got[0].LastError)
+			}
+		})
+	}
+}
+
+func TestDispatcherWaitsForRetry(t *testing.T) {
+	db, err := repository.InitDB(repository.InMemory, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := repository.EnqueueNotification(db, &model.Notification{ChatID: -100, Text: "Новая запись"}); err != nil {
+		t.Fatal(err)
+	}
+	sender := &telegramtest.FlakySender{Errs: []error{&tgbotapi.Error{Code: 500}}}
+	d := NewDispatcher(db, sender, slog.New(slog.DiscardHandler))
+
+	for range 3 {
+		if err := d.DispatchPending(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sender.Sent) != 0 {
+		t.Fatal("notification retried before its backoff elapsed")
+	}
+}
+
+func repeat(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
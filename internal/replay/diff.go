@@ -0,0 +1,105 @@
+package replay
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+// Exchange — обновление, время его получения и запросы, которые бот сделал в
+// ответ на него. Ответом считается всё, что записано до следующего обновления.
+type Exchange struct {
+	Time     time.Time
+	Update   tgbotapi.Update
+	Expected []telegram.Outgoing
+}
+
+// Exchanges разбивает запись на обмены; запросы до первого обновления пропускаются
+func Exchanges(entries []Entry) []Exchange {
+	var exchanges []Exchange
+	for _, e := range entries {
+		switch {
+		case e.Update != nil:
+			exchanges = append(exchanges, Exchange{Time: e.Time, Update: *e.Update})
+		case e.Out != nil && len(exchanges) > 0:
+			last := &exchanges[len(exchanges)-1]
+			last.Expected = append(last.Expected, *e.Out)
+		}
+	}
+	return exchanges
+}
+
+// Diff сравнивает записанные и полученные при проигрывании запросы построчно.
+// ID сообщений не сравниваются: при проигрывании они другие. Возвращает
+// пустой срез, если ответы совпали.
+func Diff(expected, actual []telegram.Outgoing) []string {
+	var diff []string
+	for i := 0; i < max(len(expected), len(actual)); i++ {
+		var want, got string
+		if i < len(expected) {
+			want = expected[i].String()
+		}
+		if i < len(actual) {
+			got = actual[i].String()
+		}
+		if want == got {
+			continue
+		}
+		if want != "" {
+			diff = append(diff, "- "+want)
+		}
+		if got != "" {
+			diff = append(diff, "+ "+got)
+		}
+	}
+	return diff
+}
+
+// Capture — Sender для проигрывания: запоминает запросы и отвечает успехом
+type Capture struct {
+	mu            sync.Mutex
+	out           []telegram.Outgoing
+	lastMessageID int
+}
+
+func (c *Capture) Send(ch tgbotapi.Chattable) (tgbotapi.Message, error) {
+	out := c.record(ch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastMessageID++
+	return tgbotapi.Message{MessageID: c.lastMessageID, Chat: &tgbotapi.Chat{ID: out.ChatID}, Text: out.Text}, nil
+}
+
+func (c *Capture) Request(ch tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	c.record(ch)
+	return &tgbotapi.APIResponse{Ok: true, Result: json.RawMessage("true")}, nil
+}
+
+func (c *Capture) record(ch tgbotapi.Chattable) telegram.Outgoing {
+	out := telegram.Describe(ch)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out = append(c.out, out)
+	return out
+}
+
+// Take возвращает запросы, накопленные с прошлого вызова
+func (c *Capture) Take() []telegram.Outgoing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.out
+	c.out = nil
+	return out
+}
+
+// LastMessageID возвращает ID последнего отправленного сообщения
+func (c *Capture) LastMessageID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastMessageID
+}
@@ -0,0 +1,105 @@
+// Package replay записывает переписку бота в JSONL и проигрывает её заново,
+// чтобы воспроизводить жалобы пользователей и проверять регрессии
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+// Entry — строка записи: входящее обновление или исходящий запрос бота
+type Entry struct {
+	Time   time.Time          `json:"time"`
+	Update *tgbotapi.Update   `json:"update,omitempty"`
+	Out    *telegram.Outgoing `json:"out,omitempty"`
+}
+
+// Recorder дописывает обновления и запросы бота в JSONL-файл. Запись содержит
+// персональные данные пациентов без маскировки.
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// Open открывает файл записи для дописывания
+func Open(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Update записывает входящее обновление
+func (r *Recorder) Update(update tgbotapi.Update) error {
+	return r.write(Entry{Time: time.Now(), Update: &update})
+}
+
+// Wrap возвращает Sender, который записывает каждый запрос перед отправкой в next
+func (r *Recorder) Wrap(next telegram.Sender) telegram.Sender {
+	return &recordingSender{next: next, rec: r}
+}
+
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
+
+func (r *Recorder) write(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(e)
+}
+
+type recordingSender struct {
+	next telegram.Sender
+	rec  *Recorder
+}
+
+func (s *recordingSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.record(c)
+	return s.next.Send(c)
+}
+
+func (s *recordingSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	s.record(c)
+	return s.next.Request(c)
+}
+
+func (s *recordingSender) record(c tgbotapi.Chattable) {
+	out := telegram.Describe(c)
+	// Ошибка записи не должна мешать ответу пользователю
+	_ = s.rec.write(Entry{Time: time.Now(), Out: &out})
+}
+
+// ReadFile читает запись; строки, которые не удалось разобрать, считаются ошибкой
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// Availability holds the clinic's working hours and the datetimes taken by
+// active bookings or held by open waitlist offers for a range of dates. It is
+// loaded with a few queries, after which free slots for any date in the range
+// are computed in memory.
+type Availability struct {
+	from, to time.Time // inclusive
+	hours    map[time.Weekday]workingHours
+	booked   map[string]bool // YYYY-MM-DD HH:MM
+	held     map[string]bool // offered to a waitlisted patient until the claim expires
+}
+
+// workingHours is the opening and closing time of a working day, as offsets
+// from midnight
+type workingHours struct {
+	start, end time.Duration
+}
+
+// LoadAvailability loads working hours, active bookings and open waitlist offers
+// for the dates from through to (YYYY-MM-DD, inclusive)
+func LoadAvailability(db DBTX, from, to string) (*Availability, error) {
+	fromDay, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+	toDay, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	a := &Availability{
+		from:   fromDay,
+		to:     toDay,
+		hours:  make(map[time.Weekday]workingHours),
+		booked: make(map[string]bool),
+		held:   make(map[string]bool),
+	}
+	if err := a.loadHours(db); err != nil {
+		return nil, err
+	}
+
+	end := toDay.AddDate(0, 0, 1).Format("2006-01-02")
+	err = loadDatetimes(db, a.booked, `
+        SELECT datetime
+        FROM bookings
+        WHERE datetime >= ? AND datetime < ? AND status != ?`,
+		from, end, model.StatusCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get booked slots: %w", err)
+	}
+
+	// An offer holds its slot until it is claimed, declined or expires
+	err = loadDatetimes(db, a.held, `
+        SELECT datetime
+        FROM slot_offers
+        WHERE datetime >= ? AND datetime < ? AND status = ? AND expires_at > CURRENT_TIMESTAMP`,
+		from, end, model.OfferPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offered slots: %w", err)
+	}
+	return a, nil
+}
+
+// loadDatetimes adds the datetimes returned by the query to set
+func loadDatetimes(db DBTX, set map[string]bool, query string, args ...any) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var datetime string
+		if err := rows.Scan(&datetime); err != nil {
+			return err
+		}
+		set[datetime] = true
+	}
+	return rows.Err()
+}
+
+// loadHours reads working hours for every working day of the week
+func (a *Availability) loadHours(db DBTX) error {
+	rows, err := db.Query(`
+        SELECT day_of_week, start_time, end_time
+        FROM working_hours
+        WHERE is_working = 1`)
+	if err != nil {
+		return fmt.Errorf("failed to get working hours: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var day time.Weekday
+		var startTime, endTime string
+		if err := rows.Scan(&day, &startTime, &endTime); err != nil {
+			return err
+		}
+		start, err := time.Parse("15:04", startTime)
+		if err != nil {
+			return fmt.Errorf("invalid start time format: %w", err)
+		}
+		end, err := time.Parse("15:04", endTime)
+		if err != nil {
+			return fmt.Errorf("invalid end time format: %w", err)
+		}
+		if _, ok := a.hours[day]; !ok {
+			a.hours[day] = workingHours{start: sinceMidnight(start), end: sinceMidnight(end)}
+		}
+	}
+	return rows.Err()
+}
+
+// Slots returns free slots for the date (YYYY-MM-DD). Days off and dates
+// outside the loaded range have no slots.
+func (a *Availability) Slots(date string) []string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil || day.Before(a.from) || day.After(a.to) {
+		return nil
+	}
+	hours, ok := a.hours[day.Weekday()]
+	if !ok {
+		return nil
+	}
+
+	var slots []string
+	for offset := hours.start; offset < hours.end; offset += SlotDuration {
+		slot := day.Add(offset).Format("2006-01-02 15:04")
+		if !a.booked[slot] && !a.held[slot] {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// SlotsAfter returns free slots for the date that start after now, so that times
+// which have already passed today are not offered
+func (a *Availability) SlotsAfter(date string, now time.Time) []string {
+	current := now.Format("2006-01-02 15:04")
+	if date > current[:10] {
+		return a.Slots(date)
+	}
+	var slots []string
+	for _, slot := range a.Slots(date) {
+		if slot > current {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// IsAvailable reports whether datetime (YYYY-MM-DD HH:MM) is one of the slots of
+// its day, as Slots lists them: it starts on the slot grid from opening time and
+// before closing time, is not taken by an active booking and not held by an open
+// offer
+func (a *Availability) IsAvailable(datetime string) bool {
+	t, err := time.Parse("2006-01-02 15:04", datetime)
+	if err != nil {
+		return false
+	}
+	day := t.Truncate(24 * time.Hour)
+	if day.Before(a.from) || day.After(a.to) {
+		return false
+	}
+	hours, ok := a.hours[t.Weekday()]
+	if !ok {
+		return false
+	}
+	offset := t.Sub(day)
+	if offset < hours.start || offset >= hours.end || (offset-hours.start)%SlotDuration != 0 {
+		return false
+	}
+	return !a.booked[datetime] && !a.held[datetime]
+}
+
+// Release makes a slot held by an offer available again, for the patient
+// claiming that offer
+func (a *Availability) Release(datetime string) {
+	delete(a.held, datetime)
+}
+
+// sinceMidnight converts a clock time parsed with "15:04" to an offset from midnight
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
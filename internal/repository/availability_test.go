@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// availabilityDays — горизонт подсказки ближайших дат в сервисе записи
+const availabilityDays = 60
+
+// testDB открывает базу в памяти и занимает каждый третий слот на days дней вперёд от 2030-01-07.
+// Каждая пятая из этих заявок отменена и слот не занимает.
+func testDB(tb testing.TB, days int) *sql.DB {
+	tb.Helper()
+	db, err := InitDB(InMemory, slog.New(slog.DiscardHandler))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	first := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	n := 0
+	for day := 0; day < days; day++ {
+		for slot := first.AddDate(0, 0, day).Add(9 * time.Hour); slot.Hour() < 18; slot = slot.Add(3 * SlotDuration) {
+			b := &model.Booking{Name: "Пациент", Phone: "+7 900 000-00-00", Service: "Чистка", DateTime: slot.Format("2006-01-02 15:04")}
+			if err := SaveBooking(db, b); err != nil {
+				tb.Fatal(err)
+			}
+			if n++; n%5 == 0 {
+				if _, err := db.Exec(`UPDATE bookings SET status = ? WHERE id = ?`, model.StatusCancelled, b.ID); err != nil {
+					tb.Fatal(err)
+				}
+			}
+		}
+	}
+	return db
+}
+
+// perSlotTimeSlots — прежний расчёт: рабочие часы и занятость запрашиваются для каждого слота отдельно
+func perSlotTimeSlots(db *sql.DB, date string) ([]string, error) {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, err
+	}
+	var startTime, endTime string
+	err = db.QueryRow(`SELECT start_time, end_time FROM working_hours WHERE day_of_week = ? AND is_working = 1`,
+		t.Weekday()).Scan(&startTime, &endTime)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := time.Parse("15:04", startTime)
+	end, _ := time.Parse("15:04", endTime)
+
+	var slots []string
+	for offset := sinceMidnight(start); offset < sinceMidnight(end); offset += SlotDuration {
+		slot := t.Add(offset).Format("2006-01-02 15:04")
+		// Как IsDateTimeAvailable раньше: рабочие часы перечитываются для каждого слота
+		if err := db.QueryRow(`SELECT start_time, end_time FROM working_hours WHERE day_of_week = ? AND is_working = 1`,
+			t.Weekday()).Scan(&startTime, &endTime); err != nil {
+			return nil, err
+		}
+		var exists bool
+		if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM bookings WHERE datetime = ? AND status != 'cancelled')`,
+			slot).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+func TestAvailability(t *testing.T) {
+	db := testDB(t, 14)
+	availability, err := LoadAvailability(db, "2030-01-06", "2030-01-20")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Свободные слоты совпадают с прежним расчётом по одному слоту
+	for day := time.Date(2030, 1, 6, 0, 0, 0, 0, time.UTC); day.Day() <= 20; day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		want, err := perSlotTimeSlots(db, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := availability.Slots(date); !slices.Equal(got, want) {
+			t.Errorf("%s: slots %v, want %v", date, got, want)
+		}
+	}
+	if slots := availability.Slots("2030-01-21"); slots != nil {
+		t.Errorf("slots outside the loaded range: %v", slots)
+	}
+
+	// Сегодня предлагается только время, которое ещё не прошло
+	now := time.Date(2030, 1, 12, 13, 40, 0, 0, time.UTC)
+	if got, want := availability.SlotsAfter("2030-01-12", now), []string{"2030-01-12 14:00", "2030-01-12 14:30"}; !slices.Equal(got, want) {
+		t.Errorf("slots after %v: %v, want %v", now, got, want)
+	}
+	if got := availability.SlotsAfter("2030-01-11", now); len(got) > 0 {
+		t.Errorf("slots on a past day: %v", got)
+	}
+	if got := availability.SlotsAfter("2030-01-14", now); !slices.Equal(got, availability.Slots("2030-01-14")) {
+		t.Errorf("slots on a future day: %v", got)
+	}
+
+	tests := []struct {
+		datetime string
+		want     bool
+	}{
+		{"2030-01-07 09:00", false}, // занято
+		{"2030-01-07 09:30", true},
+		{"2030-01-07 08:30", false}, // до открытия
+		{"2030-01-07 17:30", true},  // последний слот
+		{"2030-01-07 18:00", false}, // время закрытия
+		{"2030-01-07 10:17", false}, // между слотами
+		{"2030-01-06 12:00", false}, // воскресенье
+		{"2030-01-12 15:30", false}, // суббота после закрытия
+		{"2030-01-21 10:00", false}, // вне загруженного диапазона
+	}
+	for _, tt := range tests {
+		if got := availability.IsAvailable(tt.datetime); got != tt.want {
+			t.Errorf("IsAvailable(%s) = %v, want %v", tt.datetime, got, tt.want)
+		}
+	}
+
+	// Отменённая заявка слот не занимает
+	var cancelled string
+	if err := db.QueryRow(`SELECT datetime FROM bookings WHERE status = ? LIMIT 1`, model.StatusCancelled).Scan(&cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := IsDateTimeAvailable(db, cancelled); err != nil || !ok {
+		t.Errorf("IsDateTimeAvailable(%s) = %v, %v for a cancelled booking", cancelled, ok, err)
+	}
+}
+
+func BenchmarkTimeSlots(b *testing.B) {
+	db := testDB(b, availabilityDays)
+	const date = "2030-01-09"
+
+	b.Run("per-slot", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := perSlotTimeSlots(db, date); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("availability", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := GetAvailableTimeSlots(db, date); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkRange сравнивает поиск свободных дней на горизонте подсказки ближайших дат
+func BenchmarkRange(b *testing.B) {
+	db := testDB(b, availabilityDays)
+	first := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 0, availabilityDays-1)
+
+	b.Run("per-slot", func(b *testing.B) {
+		for b.Loop() {
+			for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+				if _, err := perSlotTimeSlots(db, day.Format("2006-01-02")); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("availability", func(b *testing.B) {
+		for b.Loop() {
+			availability, err := LoadAvailability(db, first.Format("2006-01-02"), last.Format("2006-01-02"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+				availability.Slots(day.Format("2006-01-02"))
+			}
+		}
+	})
+}
@@ -2,216 +2,431 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 
 	"github.com/REmakerzz/dental-clinic-bot/internal/model"
 )
 
-func SaveBooking(db *sql.DB, booking *model.Booking) error {
-	stmt, err := db.Prepare(`INSERT INTO bookings (name, phone, service, datetime) VALUES (?, ?, ?, ?)`)
+// SlotDuration is the length of one appointment slot
+const SlotDuration = 30 * time.Minute
+
+// ErrSlotUnavailable is returned when the requested time is outside working hours
+// or already taken by another booking
+var ErrSlotUnavailable = errors.New("this time slot is not available")
+
+// ErrSlotPassed is returned when the requested time has already started or passed
+var ErrSlotPassed = errors.New("this time slot has already passed")
+
+// SaveBooking inserts a new booking and sets its ID and status
+func SaveBooking(db DBTX, booking *model.Booking) error {
+	var patientID any
+	if booking.PatientID != 0 {
+		patientID = booking.PatientID
+	}
+	res, err := db.Exec(`INSERT INTO bookings (name, phone, service, datetime, patient_id) VALUES (?, ?, ?, ?, ?)`,
+		booking.Name, booking.Phone, booking.Service, booking.DateTime, patientID)
 	if err != nil {
-		return err
+		return slotConflict(err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(booking.Name, booking.Phone, booking.Service, booking.DateTime)
+	id, err := res.LastInsertId()
 	if err != nil {
 		return err
 	}
+	booking.ID = int(id)
+	booking.Status = model.StatusNew
 
-	log.Printf("Saved booking: %+v", booking)
 	return nil
 }
 
-func GetAllBookings(db *sql.DB) ([]*model.Booking, error) {
-	rows, err := db.Query(`SELECT id, name, phone, service, datetime FROM bookings ORDER BY id DESC`)
+// UpdateBooking overwrites the patient details, service and time of an existing booking
+func UpdateBooking(db *sql.DB, booking *model.Booking) error {
+	res, err := db.Exec(`UPDATE bookings SET name = ?, phone = ?, service = ?, datetime = ?,
+        sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		booking.Name, booking.Phone, booking.Service, booking.DateTime, booking.ID)
 	if err != nil {
-		return nil, err
+		return slotConflict(err)
 	}
-	defer rows.Close()
 
-	var bookings []*model.Booking
-	for rows.Next() {
-		var b model.Booking
-		err := rows.Scan(&b.ID, &b.Name, &b.Phone, &b.Service, &b.DateTime)
-		if err != nil {
-			return nil, err
-		}
-		bookings = append(bookings, &b)
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
 	}
 
-	return bookings, nil
+	return nil
 }
 
-func GetBookingStats(db *sql.DB) (total int, today int, last7Days int, err error) {
-	err = db.QueryRow(`SELECT COUNT(*) FROM bookings`).Scan(&total)
+// SetBookingStatus changes the status of a booking
+func SetBookingStatus(db *sql.DB, id int, status string) error {
+	res, err := db.Exec(`UPDATE bookings SET status = ?, sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?`, status, id)
 	if err != nil {
-		return
+		return slotConflict(err)
 	}
-
-	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	todayEnd := todayStart.Add(24 * time.Hour)
-
-	err = db.QueryRow(`SELECT COUNT(*) FROM bookings WHERE created_at >= ? AND created_at < ?`,
-		todayStart.Format("2006-01-02 15:04:05"), todayEnd.Format("2006-01-02 15:04:05")).Scan(&today)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return
+		return err
 	}
-
-	last7DaysStart := now.AddDate(0, 0, -7)
-	err = db.QueryRow(`SELECT COUNT(*) FROM bookings WHERE created_at >= ?`,
-		last7DaysStart.Format("2006-01-02 15:04:05")).Scan(&last7Days)
-	if err != nil {
-		return
+	if affected == 0 {
+		return sql.ErrNoRows
 	}
-
-	return
+	return nil
 }
 
-func DeleteBookingByID(db *sql.DB, id int) error {
-	stmt, err := db.Prepare(`DELETE FROM bookings WHERE id = ?`)
+// SetVisitNote saves the doctor's note on a visit
+func SetVisitNote(db *sql.DB, id int, note string) error {
+	res, err := db.Exec(`UPDATE bookings SET visit_note = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, note, id)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-
-	res, err := stmt.Exec(id)
-	if err != nil {
-		return err
-	}
-
 	affected, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
-
 	if affected == 0 {
 		return sql.ErrNoRows
 	}
-
 	return nil
 }
 
-// IsDateTimeAvailable checks if the given datetime is available for booking
-func IsDateTimeAvailable(db *sql.DB, datetime string) (bool, error) {
-	// First check if the time is within working hours
-	t, err := time.Parse("2006-01-02 15:04", datetime)
-	if err != nil {
-		return false, fmt.Errorf("invalid datetime format: %w", err)
+// slotConflict maps a violation of the active booking uniqueness index to
+// ErrSlotUnavailable; it happens when two people grab the same slot at once.
+func slotConflict(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return ErrSlotUnavailable
 	}
+	return err
+}
 
-	// Get working hours for the day
-	var startTime, endTime string
-	err = db.QueryRow(`
-        SELECT start_time, end_time 
-        FROM working_hours 
-        WHERE day_of_week = ? AND is_working = 1`,
-		t.Weekday()).Scan(&startTime, &endTime)
+func GetAllBookings(db *sql.DB) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT ` + bookingColumns + ` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        ORDER BY b.id DESC`)
 	if err != nil {
-		return false, fmt.Errorf("failed to get working hours: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
 
-	// Parse working hours
-	start, err := time.Parse("15:04", startTime)
+// GetBookingByID returns a single booking or sql.ErrNoRows
+func GetBookingByID(db *sql.DB, id int) (*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.id = ?`, id)
 	if err != nil {
-		return false, fmt.Errorf("invalid start time format: %w", err)
+		return nil, err
 	}
-	end, err := time.Parse("15:04", endTime)
+	defer rows.Close()
+
+	bookings, err := scanBookings(rows)
 	if err != nil {
-		return false, fmt.Errorf("invalid end time format: %w", err)
+		return nil, err
+	}
+	if len(bookings) == 0 {
+		return nil, sql.ErrNoRows
 	}
+	return bookings[0], nil
+}
 
-	// Check if the time is within working hours
-	bookingTime := time.Date(0, 0, 0, t.Hour(), t.Minute(), 0, 0, time.UTC)
-	if bookingTime.Before(start) || bookingTime.After(end) {
-		return false, nil
+// FindBookings returns one page of bookings matching the filter. Bookings are
+// ordered by appointment time when a date range is given, newest first otherwise.
+func FindBookings(db *sql.DB, filter model.BookingFilter, limit, offset int) ([]*model.Booking, error) {
+	where, args := bookingFilterWhere(filter)
+
+	order := `b.id DESC`
+	if filter.DateFrom != "" || filter.DateTo != "" {
+		order = `b.datetime, b.id`
 	}
 
-	// Check if the time slot is already booked
-	var exists bool
-	err = db.QueryRow(`
-        SELECT EXISTS(
-            SELECT 1 FROM bookings 
-            WHERE datetime = ?
-        )`, datetime).Scan(&exists)
+	query := `SELECT ` + bookingColumns + ` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id` + where + `
+        ORDER BY ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := db.Query(query, append(args, limit, offset)...)
 	if err != nil {
-		return false, fmt.Errorf("failed to check booking existence: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	return !exists, nil
+	return scanBookings(rows)
 }
 
-// GetAvailableTimeSlots returns available time slots for a given date
-func GetAvailableTimeSlots(db *sql.DB, date string) ([]string, error) {
-	// Parse the date
-	t, err := time.Parse("2006-01-02", date)
+// GetDayBookings returns the bookings of a day (YYYY-MM-DD) that are not cancelled,
+// grouped by doctor and ordered by time within each doctor
+func GetDayBookings(db *sql.DB, date string) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.datetime >= ? AND b.datetime < ? AND b.status != ?
+        ORDER BY COALESCE(d.name, ''), b.datetime`, date, date+"~", model.StatusCancelled)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Get working hours for the day
-	var startTime, endTime string
-	err = db.QueryRow(`
-        SELECT start_time, end_time 
-        FROM working_hours 
-        WHERE day_of_week = ? AND is_working = 1`,
-		t.Weekday()).Scan(&startTime, &endTime)
+	return scanBookings(rows)
+}
+
+// GetBookingsInRange returns all bookings, cancelled included, with an appointment
+// between from and to (YYYY-MM-DD, inclusive) ordered by time
+func GetBookingsInRange(db *sql.DB, from, to string) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.datetime >= ? AND b.datetime < ?
+        ORDER BY b.datetime`, from, to+"~")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get working hours: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Parse working hours
-	start, err := time.Parse("15:04", startTime)
-	if err != nil {
-		return nil, fmt.Errorf("invalid start time format: %w", err)
+	return scanBookings(rows)
+}
+
+// CountBookings returns the number of bookings matching the filter
+func CountBookings(db *sql.DB, filter model.BookingFilter) (int, error) {
+	where, args := bookingFilterWhere(filter)
+
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id`+where, args...).Scan(&count)
+	return count, err
+}
+
+// listFilterDays is how long the filter of a sent booking list is kept for paging
+const listFilterDays = 30
+
+// SaveListFilter remembers the filter of a booking list message and forgets
+// filters of lists older than listFilterDays
+func SaveListFilter(db *sql.DB, chatID int64, messageID int, f model.BookingFilter) error {
+	if _, err := db.Exec(`DELETE FROM list_filters WHERE created_at < datetime('now', ?)`,
+		"-"+strconv.Itoa(listFilterDays)+" days"); err != nil {
+		return err
 	}
-	end, err := time.Parse("15:04", endTime)
+	_, err := db.Exec(`INSERT OR REPLACE INTO list_filters (chat_id, message_id, date_from, date_to, status, service, doctor)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`, chatID, messageID, f.DateFrom, f.DateTo, f.Status, f.Service, f.Doctor)
+	return err
+}
+
+// GetListFilter returns the filter of a booking list message or sql.ErrNoRows
+func GetListFilter(db *sql.DB, chatID int64, messageID int) (model.BookingFilter, error) {
+	var f model.BookingFilter
+	err := db.QueryRow(`SELECT date_from, date_to, status, service, doctor FROM list_filters
+        WHERE chat_id = ? AND message_id = ?`, chatID, messageID).
+		Scan(&f.DateFrom, &f.DateTo, &f.Status, &f.Service, &f.Doctor)
+	return f, err
+}
+
+// MinSearchLength is the shortest name or phone fragment SearchBookings accepts;
+// the trigram index cannot match anything shorter.
+const MinSearchLength = 3
+
+// ErrSearchQueryTooShort is returned by SearchBookings for fragments the index cannot match
+var ErrSearchQueryTooShort = errors.New("search query is too short")
+
+// SearchBookings finds bookings by booking ID, a fragment of the patient's name
+// (case-insensitive, "ё" and "е" are equal) or a fragment of the phone digits.
+// Every word of a name query has to match. Newest appointments come first.
+func SearchBookings(db *sql.DB, query string, limit int) ([]*model.Booking, error) {
+	query = strings.TrimPrefix(strings.TrimSpace(query), "#")
+
+	var conds []string
+	var args []any
+	if digits := phoneDigits(query); digits != "" && strings.Trim(query, "0123456789 -()+.") == "" {
+		if id, err := strconv.Atoi(digits); err == nil {
+			conds = append(conds, `b.id = ?`)
+			args = append(args, id)
+		}
+		if utf8.RuneCountInString(digits) >= MinSearchLength {
+			conds = append(conds, `b.id IN (SELECT rowid FROM booking_search WHERE phone MATCH ?)`)
+			args = append(args, ftsPhrase(digits))
+		}
+	} else {
+		var phrases []string
+		for _, word := range strings.Fields(normalizeSearchName(query)) {
+			if utf8.RuneCountInString(word) >= MinSearchLength {
+				phrases = append(phrases, ftsPhrase(word))
+			}
+		}
+		if len(phrases) > 0 {
+			conds = append(conds, `b.id IN (SELECT rowid FROM booking_search WHERE name MATCH ?)`)
+			args = append(args, strings.Join(phrases, " AND "))
+		}
+	}
+
+	if len(conds) == 0 {
+		return nil, ErrSearchQueryTooShort
+	}
+
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE `+strings.Join(conds, " OR ")+`
+        ORDER BY b.datetime DESC, b.id DESC LIMIT ?`, append(args, limit)...)
 	if err != nil {
-		return nil, fmt.Errorf("invalid end time format: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
+
+func normalizeSearchName(s string) string {
+	return strings.NewReplacer("ё", "е", "Ё", "Е").Replace(s)
+}
+
+func phoneDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
 
-	// Generate time slots (30-minute intervals)
-	var slots []string
-	current := time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), 0, 0, t.Location())
-	slotEnd := time.Date(t.Year(), t.Month(), t.Day(), end.Hour(), end.Minute(), 0, 0, t.Location())
+// ftsPhrase quotes a term as an FTS5 phrase so that user input is never parsed as query syntax
+func ftsPhrase(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
 
-	for current.Before(slotEnd) {
-		slot := current.Format("2006-01-02 15:04")
+const bookingColumns = `b.id, b.name, b.phone, b.service, b.datetime, b.status,
+        COALESCE(b.doctor_id, 0), COALESCE(d.name, ''), COALESCE(b.created_at, ''),
+        COALESCE(b.updated_at, b.created_at, ''), b.sequence, b.visit_note,
+        COALESCE(b.patient_id, 0)`
 
-		// Check if the slot is available
-		available, err := IsDateTimeAvailable(db, slot)
+func scanBookings(rows *sql.Rows) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	for rows.Next() {
+		var b model.Booking
+		err := rows.Scan(&b.ID, &b.Name, &b.Phone, &b.Service, &b.DateTime, &b.Status,
+			&b.DoctorID, &b.Doctor, &b.CreatedAt, &b.UpdatedAt, &b.Sequence, &b.VisitNote,
+			&b.PatientID)
 		if err != nil {
 			return nil, err
 		}
+		bookings = append(bookings, &b)
+	}
 
-		if available {
-			slots = append(slots, slot)
-		}
+	return bookings, rows.Err()
+}
+
+func bookingFilterWhere(f model.BookingFilter) (string, []any) {
+	var conds []string
+	var args []any
+
+	if f.DateFrom != "" {
+		conds = append(conds, `b.datetime >= ?`)
+		args = append(args, f.DateFrom)
+	}
+	if f.DateTo != "" {
+		// datetime is "YYYY-MM-DD HH:MM", so every time of the last day sorts below "YYYY-MM-DD~"
+		conds = append(conds, `b.datetime < ?`)
+		args = append(args, f.DateTo+"~")
+	}
+	if f.Status != "" {
+		conds = append(conds, `b.status = ?`)
+		args = append(args, f.Status)
+	}
+	if f.Service != "" {
+		conds = append(conds, `ulower(b.service) LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(f.Service))
+	}
+	if f.Doctor != "" {
+		conds = append(conds, `ulower(d.name) LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(f.Doctor))
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return "\n        WHERE " + strings.Join(conds, " AND "), args
+}
+
+// likePattern turns a user supplied fragment into a case-insensitive LIKE pattern
+func likePattern(fragment string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(strings.ToLower(fragment)) + "%"
+}
+
+func GetBookingStats(db *sql.DB) (total int, today int, last7Days int, err error) {
+	err = db.QueryRow(`SELECT COUNT(*) FROM bookings`).Scan(&total)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	todayEnd := todayStart.Add(24 * time.Hour)
+
+	err = db.QueryRow(`SELECT COUNT(*) FROM bookings WHERE created_at >= ? AND created_at < ?`,
+		todayStart.Format("2006-01-02 15:04:05"), todayEnd.Format("2006-01-02 15:04:05")).Scan(&today)
+	if err != nil {
+		return
+	}
+
+	last7DaysStart := now.AddDate(0, 0, -7)
+	err = db.QueryRow(`SELECT COUNT(*) FROM bookings WHERE created_at >= ?`,
+		last7DaysStart.Format("2006-01-02 15:04:05")).Scan(&last7Days)
+	if err != nil {
+		return
+	}
 
-		current = current.Add(30 * time.Minute)
+	return
+}
+
+// IsDateTimeAvailable checks if the given datetime is available for booking
+func IsDateTimeAvailable(db *sql.DB, datetime string) (bool, error) {
+	if _, err := time.Parse("2006-01-02 15:04", datetime); err != nil {
+		return false, fmt.Errorf("invalid datetime format: %w", err)
 	}
+	date := datetime[:10]
+	availability, err := LoadAvailability(db, date, date)
+	if err != nil {
+		return false, err
+	}
+	return availability.IsAvailable(datetime), nil
+}
 
-	return slots, nil
+// GetAvailableTimeSlots returns available time slots for a given date
+func GetAvailableTimeSlots(db *sql.DB, date string) ([]string, error) {
+	availability, err := LoadAvailability(db, date, date)
+	if err != nil {
+		return nil, err
+	}
+	return availability.Slots(date), nil
 }
 
-// ValidateDateTime checks if the datetime is in correct format and within working hours
-func ValidateDateTime(db *sql.DB, datetime string) error {
+// ValidateDateTime checks if the datetime is in correct format, still ahead of now
+// and within working hours
+func ValidateDateTime(db *sql.DB, datetime string, now time.Time) error {
 	// Check format
 	_, err := time.Parse("2006-01-02 15:04", datetime)
 	if err != nil {
 		return fmt.Errorf("invalid datetime format. Please use YYYY-MM-DD HH:MM format")
 	}
 
+	// A time button may be pressed after that time has come
+	if datetime <= now.Format("2006-01-02 15:04") {
+		return ErrSlotPassed
+	}
+
 	// Check if available
 	available, err := IsDateTimeAvailable(db, datetime)
 	if err != nil {
 		return err
 	}
 	if !available {
-		return fmt.Errorf("this time slot is not available")
+		return ErrSlotUnavailable
 	}
 
 	return nil
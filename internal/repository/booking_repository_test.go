@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// TestIsDateTimeAvailable проверяет границы рабочего дня. Раньше время приёма
+// сравнивалось с часами работы на разных датах, и ни один слот не был свободен.
+func TestIsDateTimeAvailable(t *testing.T) {
+	db, err := InitDB(InMemory, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := SaveBooking(db, &model.Booking{Name: "Пациент", Phone: "+7 900 000-00-00", Service: "Чистка", DateTime: "2030-01-07 11:00"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		datetime string
+		want     bool
+	}{
+		{"2030-01-07 09:00", true}, // открытие
+		{"2030-01-07 10:30", true},
+		{"2030-01-07 11:00", false}, // занято
+		{"2030-01-07 08:30", false},
+		{"2030-01-07 18:30", false},
+		{"2030-01-12 10:00", true}, // суббота
+		{"2030-01-12 15:30", false},
+		{"2030-01-06 10:00", false}, // воскресенье
+	}
+	for _, tt := range tests {
+		got, err := IsDateTimeAvailable(db, tt.datetime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsDateTimeAvailable(%s) = %v, want %v", tt.datetime, got, tt.want)
+		}
+	}
+	if _, err := IsDateTimeAvailable(db, "2030-01-07"); err == nil {
+		t.Error("no error for a datetime without time")
+	}
+
+	// Наступившее время не записывается, даже если оно свободно
+	now := time.Date(2030, 1, 7, 10, 30, 0, 0, time.UTC)
+	for datetime, want := range map[string]error{
+		"2030-01-07 10:00": ErrSlotPassed,
+		"2030-01-07 10:30": ErrSlotPassed,
+		"2030-01-07 11:00": ErrSlotUnavailable,
+		"2030-01-07 11:30": nil,
+	} {
+		if err := ValidateDateTime(db, datetime, now); !errors.Is(err, want) {
+			t.Errorf("ValidateDateTime(%s) = %v, want %v", datetime, err, want)
+		}
+	}
+}
@@ -2,18 +2,46 @@ package repository
 
 import (
 	"database/sql"
-	"log"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"strings"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
-func InitDB() *sql.DB {
-	db, err := sql.Open("sqlite", "clinic.db")
+func init() {
+	// SQLite's built-in lower() only folds ASCII, which is useless for Cyrillic
+	// names and services, so register a Unicode-aware variant.
+	sqlite.MustRegisterDeterministicScalarFunction("ulower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case nil:
+				return nil, nil
+			default:
+				return v, nil
+			}
+		})
+}
+
+// InMemory is a database path for a private in-memory database, used by tests
+const InMemory = ":memory:"
+
+// InitDB opens the database file at path, creates the base tables and applies
+// pending migrations, logging each applied migration
+func InitDB(path string, logger *slog.Logger) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	if path == InMemory {
+		// Every connection would get its own empty in-memory database
+		db.SetMaxOpenConns(1)
 	}
 
-	// Create bookings table with unique constraint on datetime
+	// Create bookings table; later columns and indexes are added by migrations
 	createBookingsTableSQL := `CREATE TABLE IF NOT EXISTS bookings (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
         name TEXT NOT NULL,
@@ -41,17 +69,20 @@ func InitDB() *sql.DB {
 
 	_, err = db.Exec(createBookingsTableSQL)
 	if err != nil {
-		log.Fatalf("Failed to create bookings table: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("create bookings table: %w", err)
 	}
 
 	_, err = db.Exec(createWorkingHoursTableSQL)
 	if err != nil {
-		log.Fatalf("Failed to create working hours table: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("create working hours table: %w", err)
 	}
 
 	_, err = db.Exec(createTimeSlotsTableSQL)
 	if err != nil {
-		log.Fatalf("Failed to create time slots table: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("create time slots table: %w", err)
 	}
 
 	// Insert default working hours if not exists
@@ -66,8 +97,207 @@ func InitDB() *sql.DB {
 
 	_, err = db.Exec(insertWorkingHoursSQL)
 	if err != nil {
-		log.Fatalf("Failed to insert default working hours: %v", err)
+		db.Close()
+		return nil, fmt.Errorf("insert default working hours: %w", err)
+	}
+
+	if err := migrate(db, logger); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+
+	return db, nil
+}
+
+// migrations are applied in order; PRAGMA user_version stores how many of them
+// have already been run against the database file.
+var migrations = []string{
+	// 1: booking statuses and doctors. The bookings table is rebuilt so that the
+	// slot uniqueness only applies to bookings that are not cancelled.
+	`CREATE TABLE IF NOT EXISTS doctors (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL UNIQUE,
+        is_active BOOLEAN NOT NULL DEFAULT 1
+    );
+    CREATE TABLE bookings_new (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL,
+        phone TEXT NOT NULL,
+        service TEXT NOT NULL,
+        datetime TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'new',
+        doctor_id INTEGER REFERENCES doctors(id),
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    INSERT INTO bookings_new (id, name, phone, service, datetime, created_at)
+        SELECT id, COALESCE(name, ''), COALESCE(phone, ''), COALESCE(service, ''), COALESCE(datetime, ''), created_at
+        FROM bookings;
+    DROP TABLE bookings;
+    ALTER TABLE bookings_new RENAME TO bookings;
+    CREATE UNIQUE INDEX idx_bookings_active_datetime ON bookings(datetime) WHERE status != 'cancelled';
+    CREATE INDEX idx_bookings_datetime ON bookings(datetime);
+    CREATE INDEX idx_bookings_status ON bookings(status);`,
+
+	// 2: trigram full-text index over patient names and phone digits for admin search.
+	// Names are stored with "ё" folded to "е"; the trigram tokenizer folds case itself.
+	`CREATE VIRTUAL TABLE booking_search USING fts5(name, phone, tokenize = "trigram");
+    INSERT INTO booking_search (rowid, name, phone)
+        SELECT id, ` + searchName("name") + `, ` + searchPhone("phone") + ` FROM bookings;
+    CREATE TRIGGER bookings_search_insert AFTER INSERT ON bookings BEGIN
+        INSERT INTO booking_search (rowid, name, phone)
+            VALUES (new.id, ` + searchName("new.name") + `, ` + searchPhone("new.phone") + `);
+    END;
+    CREATE TRIGGER bookings_search_update AFTER UPDATE OF name, phone ON bookings BEGIN
+        UPDATE booking_search SET name = ` + searchName("new.name") + `, phone = ` + searchPhone("new.phone") + `
+            WHERE rowid = new.id;
+    END;
+    CREATE TRIGGER bookings_search_delete AFTER DELETE ON bookings BEGIN
+        DELETE FROM booking_search WHERE rowid = old.id;
+    END;`,
+
+	// 3: calendar feeds. Doctors get a secret feed token; bookings get a SEQUENCE
+	// counter and modification time so calendar clients pick up changes.
+	`ALTER TABLE doctors ADD COLUMN feed_token TEXT;
+    UPDATE doctors SET feed_token = lower(hex(randomblob(16)));
+    CREATE UNIQUE INDEX idx_doctors_feed_token ON doctors(feed_token);
+    ALTER TABLE bookings ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE bookings ADD COLUMN updated_at TIMESTAMP;
+    CREATE INDEX idx_bookings_doctor_datetime ON bookings(doctor_id, datetime);`,
+
+	// 4: outbox of notifications to staff. Rows are written in the same transaction
+	// as the change they announce and delivered by the outbox dispatcher.
+	`CREATE TABLE outbox (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        chat_id INTEGER NOT NULL,
+        text TEXT NOT NULL,
+        reply_markup TEXT NOT NULL DEFAULT '',
+        booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_error TEXT NOT NULL DEFAULT '',
+        message_id INTEGER,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        sent_at TIMESTAMP
+    );
+    CREATE INDEX idx_outbox_pending ON outbox(next_attempt_at) WHERE status = 'pending';`,
+
+	// 5: staff roles. Owners from ADMIN_USER_IDS are not stored here.
+	`CREATE TABLE staff (
+        user_id INTEGER PRIMARY KEY,
+        role TEXT NOT NULL,
+        name TEXT NOT NULL DEFAULT '',
+        granted_by INTEGER,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );`,
+
+	// 6: doctor mode: staff with the doctor role are linked to a doctor, who can
+	// leave a note on a visit
+	`ALTER TABLE staff ADD COLUMN doctor_id INTEGER REFERENCES doctors(id) ON DELETE SET NULL;
+    ALTER TABLE bookings ADD COLUMN visit_note TEXT NOT NULL DEFAULT '';`,
+
+	// 7: patient profiles keyed by Telegram user ID, so regular patients are not
+	// asked for their name and phone again; bookings made by patients link to them
+	`CREATE TABLE patients (
+        user_id INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        phone TEXT NOT NULL,
+        birth_date TEXT NOT NULL DEFAULT '',
+        preferences TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    ALTER TABLE bookings ADD COLUMN patient_id INTEGER REFERENCES patients(user_id);
+    CREATE INDEX idx_bookings_patient ON bookings(patient_id);`,
+
+	// 8: waitlist for fully booked days. A free slot is offered to one waiting
+	// patient at a time, and never twice to the same one.
+	`CREATE TABLE waitlist (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        name TEXT NOT NULL,
+        phone TEXT NOT NULL,
+        service TEXT NOT NULL,
+        date_from TEXT NOT NULL,
+        date_to TEXT NOT NULL,
+        day_part TEXT NOT NULL DEFAULT 'any',
+        status TEXT NOT NULL DEFAULT 'waiting',
+        booking_id INTEGER REFERENCES bookings(id) ON DELETE SET NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX idx_waitlist_waiting ON waitlist(user_id) WHERE status = 'waiting';
+    CREATE TABLE slot_offers (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        waitlist_id INTEGER NOT NULL REFERENCES waitlist(id) ON DELETE CASCADE,
+        datetime TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        expires_at TIMESTAMP NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE UNIQUE INDEX idx_slot_offers_pending ON slot_offers(datetime) WHERE status = 'pending';
+    CREATE UNIQUE INDEX idx_slot_offers_entry ON slot_offers(waitlist_id, datetime);`,
+
+	// 9: filters of sent /admin_list messages, so paging keeps the filter the
+	// list was requested with
+	`CREATE TABLE list_filters (
+        chat_id INTEGER NOT NULL,
+        message_id INTEGER NOT NULL,
+        date_from TEXT NOT NULL DEFAULT '',
+        date_to TEXT NOT NULL DEFAULT '',
+        status TEXT NOT NULL DEFAULT '',
+        service TEXT NOT NULL DEFAULT '',
+        doctor TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (chat_id, message_id)
+    );`,
+}
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so that functions taking it
+// can run on their own or as part of a larger transaction
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// searchName and searchPhone build the SQL expressions that normalize a column for
+// booking_search. They must stay in sync with normalizeSearchName and phoneDigits.
+func searchName(column string) string {
+	return `replace(replace(` + column + `, 'ё', 'е'), 'Ё', 'Е')`
+}
+
+func searchPhone(column string) string {
+	expr := column
+	for _, ch := range []string{" ", "-", "(", ")", "+", "."} {
+		expr = `replace(` + expr + `, '` + ch + `', '')`
+	}
+	return expr
+}
+
+func migrate(db *sql.DB, logger *slog.Logger) error {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		logger.Info("applied database migration", "version", i+1)
 	}
 
-	return db
+	return nil
 }
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// CreateDoctor adds a doctor with a fresh calendar feed token
+func CreateDoctor(db *sql.DB, name string) (*model.Doctor, error) {
+	token, err := newFeedToken()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := db.Exec(`INSERT INTO doctors (name, feed_token) VALUES (?, ?)`, name, token)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Doctor{ID: id, Name: name, FeedToken: token, IsActive: true}, nil
+}
+
+// GetDoctors returns active doctors ordered by name
+func GetDoctors(db *sql.DB) ([]*model.Doctor, error) {
+	rows, err := db.Query(`SELECT id, name, COALESCE(feed_token, ''), is_active FROM doctors
+        WHERE is_active = 1 ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var doctors []*model.Doctor
+	for rows.Next() {
+		var d model.Doctor
+		if err := rows.Scan(&d.ID, &d.Name, &d.FeedToken, &d.IsActive); err != nil {
+			return nil, err
+		}
+		doctors = append(doctors, &d)
+	}
+	return doctors, rows.Err()
+}
+
+// GetDoctorByFeedToken returns the doctor owning a calendar feed token or sql.ErrNoRows
+func GetDoctorByFeedToken(db *sql.DB, token string) (*model.Doctor, error) {
+	var d model.Doctor
+	err := db.QueryRow(`SELECT id, name, feed_token, is_active FROM doctors WHERE feed_token = ?`, token).
+		Scan(&d.ID, &d.Name, &d.FeedToken, &d.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetDoctorByName returns the active doctor with the given name, ignoring case, or sql.ErrNoRows
+func GetDoctorByName(db *sql.DB, name string) (*model.Doctor, error) {
+	var d model.Doctor
+	err := db.QueryRow(`SELECT id, name, COALESCE(feed_token, ''), is_active FROM doctors
+        WHERE ulower(name) = ulower(?) AND is_active = 1`, name).
+		Scan(&d.ID, &d.Name, &d.FeedToken, &d.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// AssignDoctor sets the doctor of a booking; doctorID 0 removes the assignment
+func AssignDoctor(db *sql.DB, bookingID int, doctorID int64) error {
+	var doctor any
+	if doctorID != 0 {
+		doctor = doctorID
+	}
+
+	res, err := db.Exec(`UPDATE bookings SET doctor_id = ?, sequence = sequence + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?`, doctor, bookingID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDoctorBookings returns the doctor's bookings from the given date on, cancelled included
+func GetDoctorBookings(db *sql.DB, doctorID int64, from string) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.doctor_id = ? AND b.datetime >= ?
+        ORDER BY b.datetime`, doctorID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
+
+// GetDoctorSchedule returns the doctor's bookings that are not cancelled with an
+// appointment between from and to (YYYY-MM-DD, inclusive) ordered by time
+func GetDoctorSchedule(db *sql.DB, doctorID int64, from, to string) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.doctor_id = ? AND b.datetime >= ? AND b.datetime < ? AND b.status != ?
+        ORDER BY b.datetime`, doctorID, from, to+"~", model.StatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
+
+func newFeedToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// timestampFormat matches the format of CURRENT_TIMESTAMP so that values written
+// by Go and by SQLite defaults compare correctly as strings
+const timestampFormat = "2006-01-02 15:04:05"
+
+// EnqueueNotification adds a pending notification to the outbox and sets its ID.
+// Call it with the transaction that makes the change the notification is about.
+func EnqueueNotification(db DBTX, n *model.Notification) error {
+	var bookingID any
+	if n.BookingID != 0 {
+		bookingID = n.BookingID
+	}
+	res, err := db.Exec(`INSERT INTO outbox (chat_id, text, reply_markup, booking_id) VALUES (?, ?, ?, ?)`,
+		n.ChatID, n.Text, n.ReplyMarkup, bookingID)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	n.ID = id
+	n.Status = model.NotificationPending
+	return nil
+}
+
+// GetDueNotifications returns up to limit pending notifications whose next attempt
+// is due at now, oldest first
+func GetDueNotifications(db DBTX, now time.Time, limit int) ([]*model.Notification, error) {
+	rows, err := db.Query(`SELECT id, chat_id, text, reply_markup, COALESCE(booking_id, 0), status, attempts, last_error
+        FROM outbox WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`,
+		model.NotificationPending, now.UTC().Format(timestampFormat), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		n := &model.Notification{}
+		if err := rows.Scan(&n.ID, &n.ChatID, &n.Text, &n.ReplyMarkup, &n.BookingID, &n.Status, &n.Attempts, &n.LastError); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetNotifications returns notifications with the given status, oldest first
+func GetNotifications(db *sql.DB, status string) ([]*model.Notification, error) {
+	rows, err := db.Query(`SELECT id, chat_id, text, reply_markup, COALESCE(booking_id, 0), status, attempts, last_error,
+        COALESCE(message_id, 0) FROM outbox WHERE status = ? ORDER BY id`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		n := &model.Notification{}
+		if err := rows.Scan(&n.ID, &n.ChatID, &n.Text, &n.ReplyMarkup, &n.BookingID, &n.Status, &n.Attempts,
+			&n.LastError, &n.MessageID); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationSent records a successful delivery
+func MarkNotificationSent(db DBTX, id int64, messageID int) error {
+	_, err := db.Exec(`UPDATE outbox SET status = ?, attempts = attempts + 1, message_id = ?, last_error = '',
+        sent_at = CURRENT_TIMESTAMP WHERE id = ?`, model.NotificationSent, messageID, id)
+	return err
+}
+
+// MarkNotificationFailed records a failed delivery attempt. The notification is
+// retried at next unless dead is set, in which case it is never retried.
+func MarkNotificationFailed(db DBTX, id int64, lastErr string, next time.Time, dead bool) error {
+	status := model.NotificationPending
+	if dead {
+		status = model.NotificationDead
+	}
+	_, err := db.Exec(`UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+        WHERE id = ?`, status, lastErr, next.UTC().Format(timestampFormat), id)
+	return err
+}
+
+// GetBookingNotification returns the latest delivered notification about a booking
+// or sql.ErrNoRows if none was delivered
+func GetBookingNotification(db *sql.DB, bookingID int) (*model.Notification, error) {
+	n := &model.Notification{}
+	err := db.QueryRow(`SELECT id, chat_id, text, reply_markup, booking_id, status, attempts, last_error, message_id
+        FROM outbox WHERE booking_id = ? AND status = ? AND message_id != 0 ORDER BY id DESC LIMIT 1`,
+		bookingID, model.NotificationSent).Scan(&n.ID, &n.ChatID, &n.Text, &n.ReplyMarkup, &n.BookingID, &n.Status,
+		&n.Attempts, &n.LastError, &n.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// GetPatient returns the profile of a patient or sql.ErrNoRows
+func GetPatient(db *sql.DB, userID int64) (*model.Patient, error) {
+	var p model.Patient
+	err := db.QueryRow(`SELECT user_id, name, phone, birth_date, preferences, created_at, updated_at
+        FROM patients WHERE user_id = ?`, userID).
+		Scan(&p.UserID, &p.Name, &p.Phone, &p.BirthDate, &p.Preferences, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SavePatientContacts creates a patient profile or updates the name and phone of
+// an existing one, keeping the rest of the profile
+func SavePatientContacts(db DBTX, userID int64, name, phone string) error {
+	_, err := db.Exec(`INSERT INTO patients (user_id, name, phone) VALUES (?, ?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET name = excluded.name, phone = excluded.phone,
+            updated_at = CURRENT_TIMESTAMP`, userID, name, phone)
+	return err
+}
+
+// UpdatePatient overwrites the profile of an existing patient
+func UpdatePatient(db *sql.DB, p *model.Patient) error {
+	res, err := db.Exec(`UPDATE patients SET name = ?, phone = ?, birth_date = ?, preferences = ?,
+        updated_at = CURRENT_TIMESTAMP WHERE user_id = ?`, p.Name, p.Phone, p.BirthDate, p.Preferences, p.UserID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// phoneKey reduces a phone number to the digits that identify it: the last ten,
+// so that +7 999 and 8 999 are the same number
+func phoneKey(phone string) string {
+	digits := phoneDigits(phone)
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
+
+// GetPatientByPhone returns the most recently updated patient with the phone
+// number or sql.ErrNoRows
+func GetPatientByPhone(db *sql.DB, phone string) (*model.Patient, error) {
+	var p model.Patient
+	err := db.QueryRow(`SELECT user_id, name, phone, birth_date, preferences, created_at, updated_at
+        FROM patients WHERE substr(`+searchPhone("phone")+`, -10) = ?
+        ORDER BY updated_at DESC, user_id LIMIT 1`, phoneKey(phone)).
+		Scan(&p.UserID, &p.Name, &p.Phone, &p.BirthDate, &p.Preferences, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPatientBookings returns the bookings linked to the patient userID (0 for
+// none) or made with the phone number, latest appointment first
+func GetPatientBookings(db *sql.DB, userID int64, phone string) ([]*model.Booking, error) {
+	rows, err := db.Query(`SELECT `+bookingColumns+` FROM bookings b
+        LEFT JOIN doctors d ON d.id = b.doctor_id
+        WHERE b.patient_id = ? OR substr(`+searchPhone("b.phone")+`, -10) = ?
+        ORDER BY b.datetime DESC, b.id DESC`, userID, phoneKey(phone))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanBookings(rows)
+}
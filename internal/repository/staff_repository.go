@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+// GetStaffRole returns the role granted to a user or sql.ErrNoRows
+func GetStaffRole(db *sql.DB, userID int64) (string, error) {
+	var role string
+	err := db.QueryRow(`SELECT role FROM staff WHERE user_id = ?`, userID).Scan(&role)
+	return role, err
+}
+
+// GetStaffMember returns a staff member or sql.ErrNoRows
+func GetStaffMember(db *sql.DB, userID int64) (*model.Staff, error) {
+	var s model.Staff
+	err := db.QueryRow(`SELECT user_id, role, name, COALESCE(doctor_id, 0), COALESCE(granted_by, 0), created_at
+        FROM staff WHERE user_id = ?`, userID).
+		Scan(&s.UserID, &s.Role, &s.Name, &s.DoctorID, &s.GrantedBy, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetStaff returns all staff members ordered by role and name
+func GetStaff(db *sql.DB) ([]*model.Staff, error) {
+	rows, err := db.Query(`SELECT user_id, role, name, COALESCE(doctor_id, 0), COALESCE(granted_by, 0), created_at FROM staff
+        ORDER BY CASE role WHEN ? THEN 0 WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END, name, user_id`,
+		model.RoleOwner, model.RoleAdmin, model.RoleReceptionist)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var staff []*model.Staff
+	for rows.Next() {
+		var s model.Staff
+		if err := rows.Scan(&s.UserID, &s.Role, &s.Name, &s.DoctorID, &s.GrantedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		staff = append(staff, &s)
+	}
+	return staff, rows.Err()
+}
+
+// SaveStaff grants a role, replacing the role and doctor link the user had before.
+// An empty name keeps the name saved earlier.
+func SaveStaff(db *sql.DB, s *model.Staff) error {
+	var doctorID any
+	if s.DoctorID != 0 {
+		doctorID = s.DoctorID
+	}
+	_, err := db.Exec(`INSERT INTO staff (user_id, role, name, doctor_id, granted_by) VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT(user_id) DO UPDATE SET role = excluded.role, doctor_id = excluded.doctor_id,
+            granted_by = excluded.granted_by,
+            name = CASE WHEN excluded.name = '' THEN staff.name ELSE excluded.name END`,
+		s.UserID, s.Role, s.Name, doctorID, s.GrantedBy)
+	return err
+}
+
+// DeleteStaff revokes the role of a user; sql.ErrNoRows if there was none
+func DeleteStaff(db *sql.DB, userID int64) error {
+	res, err := db.Exec(`DELETE FROM staff WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
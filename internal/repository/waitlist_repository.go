@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+)
+
+const waitlistColumns = `w.id, w.user_id, w.name, w.phone, w.service, w.date_from, w.date_to, w.day_part, w.status,
+        w.created_at`
+
+func scanWaitlistEntry(row interface{ Scan(...any) error }) (*model.WaitlistEntry, error) {
+	var e model.WaitlistEntry
+	err := row.Scan(&e.ID, &e.UserID, &e.Name, &e.Phone, &e.Service, &e.DateFrom, &e.DateTo, &e.DayPart, &e.Status,
+		&e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// AddWaitlistEntry puts a patient on the waitlist and sets the entry ID and status
+func AddWaitlistEntry(db *sql.DB, e *model.WaitlistEntry) error {
+	res, err := db.Exec(`INSERT INTO waitlist (user_id, name, phone, service, date_from, date_to, day_part)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`, e.UserID, e.Name, e.Phone, e.Service, e.DateFrom, e.DateTo, e.DayPart)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	e.Status = model.WaitlistWaiting
+	return nil
+}
+
+// CountWaitingEntries returns the number of entries a patient is still waiting on
+func CountWaitingEntries(db *sql.DB, userID int64) (int, error) {
+	var n int
+	err := db.QueryRow(`SELECT COUNT(*) FROM waitlist WHERE user_id = ? AND status = ?`,
+		userID, model.WaitlistWaiting).Scan(&n)
+	return n, err
+}
+
+// GetWaitingEntries returns the entries still waiting, in the order patients joined
+func GetWaitingEntries(db *sql.DB) ([]*model.WaitlistEntry, error) {
+	rows, err := db.Query(`SELECT `+waitlistColumns+` FROM waitlist w WHERE w.status = ? ORDER BY w.id`,
+		model.WaitlistWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*model.WaitlistEntry
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ExpireWaitlist closes the waiting entries whose last date is before today (YYYY-MM-DD)
+func ExpireWaitlist(db *sql.DB, today string) (int64, error) {
+	res, err := db.Exec(`UPDATE waitlist SET status = ? WHERE status = ? AND date_to < ?`,
+		model.WaitlistExpired, model.WaitlistWaiting, today)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SetWaitlistBooked closes an entry with the booking made from it
+func SetWaitlistBooked(db DBTX, entryID int64, bookingID int) error {
+	_, err := db.Exec(`UPDATE waitlist SET status = ?, booking_id = ? WHERE id = ?`,
+		model.WaitlistBooked, bookingID, entryID)
+	return err
+}
+
+// GetWaitingOffers returns all offers made to entries that are still waiting,
+// whatever their status
+func GetWaitingOffers(db *sql.DB) ([]*model.SlotOffer, error) {
+	rows, err := db.Query(`SELECT o.id, o.waitlist_id, o.datetime, o.status, o.expires_at FROM slot_offers o
+        JOIN waitlist w ON w.id = o.waitlist_id WHERE w.status = ? ORDER BY o.id`, model.WaitlistWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offers []*model.SlotOffer
+	for rows.Next() {
+		var o model.SlotOffer
+		if err := rows.Scan(&o.ID, &o.EntryID, &o.DateTime, &o.Status, &o.ExpiresAt); err != nil {
+			return nil, err
+		}
+		offers = append(offers, &o)
+	}
+	return offers, rows.Err()
+}
+
+// CreateOffer offers a slot to a waitlist entry until expires
+func CreateOffer(db *sql.DB, entry *model.WaitlistEntry, datetime string, expires time.Time) (*model.SlotOffer, error) {
+	o := &model.SlotOffer{
+		EntryID:   entry.ID,
+		DateTime:  datetime,
+		Status:    model.OfferPending,
+		ExpiresAt: expires.UTC().Format(timestampFormat),
+		Entry:     entry,
+	}
+	res, err := db.Exec(`INSERT INTO slot_offers (waitlist_id, datetime, expires_at) VALUES (?, ?, ?)`,
+		o.EntryID, o.DateTime, o.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	o.ID, err = res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// GetOffer returns an offer together with its waitlist entry or sql.ErrNoRows
+func GetOffer(db *sql.DB, id int64) (*model.SlotOffer, error) {
+	var o model.SlotOffer
+	var e model.WaitlistEntry
+	err := db.QueryRow(`SELECT o.id, o.waitlist_id, o.datetime, o.status, o.expires_at, `+waitlistColumns+`
+        FROM slot_offers o JOIN waitlist w ON w.id = o.waitlist_id WHERE o.id = ?`, id).
+		Scan(&o.ID, &o.EntryID, &o.DateTime, &o.Status, &o.ExpiresAt,
+			&e.ID, &e.UserID, &e.Name, &e.Phone, &e.Service, &e.DateFrom, &e.DateTo, &e.DayPart, &e.Status, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	o.Entry = &e
+	return &o, nil
+}
+
+// ExpireOffers expires the pending offers that were not answered by now
+func ExpireOffers(db *sql.DB, now time.Time) (int64, error) {
+	res, err := db.Exec(`UPDATE slot_offers SET status = ? WHERE status = ? AND expires_at <= ?`,
+		model.OfferExpired, model.OfferPending, now.UTC().Format(timestampFormat))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CloseOffer moves an offer that is pending and not yet expired at now to status;
+// sql.ErrNoRows if it is no longer open
+func CloseOffer(db DBTX, id int64, status string, now time.Time) error {
+	res, err := db.Exec(`UPDATE slot_offers SET status = ? WHERE id = ? AND status = ? AND expires_at > ?`,
+		status, id, model.OfferPending, now.UTC().Format(timestampFormat))
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+// ErrBootstrapOwner is returned when changing the role of an owner listed in
+// ADMIN_USER_IDS; such owners can only be removed from the environment
+var ErrBootstrapOwner = errors.New("owner is configured in ADMIN_USER_IDS")
+
+// MemberChecker reports whether a user is a member of a chat
+type MemberChecker interface {
+	IsMember(chatID, userID int64) (bool, error)
+}
+
+// GroupAccess gives members of the admin group a role for commands sent in that group
+type GroupAccess struct {
+	ChatID  int64
+	Role    string // "" disables access by membership
+	Members MemberChecker
+}
+
+// AccessService resolves staff roles. Owners from ADMIN_USER_IDS always have the
+// owner role, so the bot stays manageable with an empty staff table. In the admin
+// group, members get at least the group role.
+type AccessService struct {
+	db     *sql.DB
+	owners []int64
+	group  GroupAccess
+	logger *slog.Logger
+}
+
+func NewAccessService(db *sql.DB, owners []int64, group GroupAccess, logger *slog.Logger) *AccessService {
+	return &AccessService{db: db, owners: owners, group: group, logger: logger}
+}
+
+// Role returns the role of a user acting in a chat, or "" for patients. If the
+// membership check fails, the user's own role is returned along with the error.
+func (s *AccessService) Role(chatID, userID int64) (string, error) {
+	role, err := s.staffRole(userID)
+	if err != nil {
+		return "", err
+	}
+
+	g := s.group
+	if chatID != g.ChatID || g.Role == "" || g.Members == nil || roleRank(role) <= roleRank(g.Role) {
+		return role, nil
+	}
+	member, err := g.Members.IsMember(chatID, userID)
+	if err != nil {
+		return role, err
+	}
+	if member {
+		return g.Role, nil
+	}
+	return role, nil
+}
+
+// staffRole returns the role granted to the user regardless of the chat
+func (s *AccessService) staffRole(userID int64) (string, error) {
+	if s.IsBootstrapOwner(userID) {
+		return model.RoleOwner, nil
+	}
+	role, err := repository.GetStaffRole(s.db, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// Can reports whether the user acting in the chat has the permission
+func (s *AccessService) Can(chatID, userID int64, permission string) bool {
+	role, err := s.Role(chatID, userID)
+	if err != nil {
+		s.logger.Error("failed to look up staff role", "chat_id", chatID, "user_id", userID, "error", err)
+	}
+	return model.HasPermission(role, permission)
+}
+
+// roleRank orders roles from the widest (0) to none
+func roleRank(role string) int {
+	if i := slices.Index(model.Roles, role); i >= 0 {
+		return i
+	}
+	return len(model.Roles)
+}
+
+// DoctorID returns the doctor linked to a user with the doctor role, or 0
+func (s *AccessService) DoctorID(userID int64) (int64, error) {
+	if s.IsBootstrapOwner(userID) {
+		return 0, nil
+	}
+	staff, err := repository.GetStaffMember(s.db, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil || staff.Role != model.RoleDoctor {
+		return 0, err
+	}
+	return staff.DoctorID, nil
+}
+
+// IsBootstrapOwner reports whether the user is an owner from ADMIN_USER_IDS
+func (s *AccessService) IsBootstrapOwner(userID int64) bool {
+	return slices.Contains(s.owners, userID)
+}
+
+// Owners returns the owners from ADMIN_USER_IDS
+func (s *AccessService) Owners() []int64 {
+	return s.owners
+}
+
+// GetStaff returns staff members stored in the database
+func (s *AccessService) GetStaff() ([]*model.Staff, error) {
+	return repository.GetStaff(s.db)
+}
+
+// Grant gives staff.UserID the role staff.Role
+func (s *AccessService) Grant(staff *model.Staff) error {
+	if s.IsBootstrapOwner(staff.UserID) {
+		return ErrBootstrapOwner
+	}
+	if err := repository.SaveStaff(s.db, staff); err != nil {
+		return err
+	}
+	s.logger.Info("staff role granted", "user_id", staff.UserID, "role", staff.Role, "granted_by", staff.GrantedBy)
+	return nil
+}
+
+// Revoke takes the role away; sql.ErrNoRows if the user had none
+func (s *AccessService) Revoke(userID, revokedBy int64) error {
+	if s.IsBootstrapOwner(userID) {
+		return ErrBootstrapOwner
+	}
+	if err := repository.DeleteStaff(s.db, userID); err != nil {
+		return err
+	}
+	s.logger.Info("staff role revoked", "user_id", userID, "revoked_by", revokedBy)
+	return nil
+}
@@ -2,42 +2,233 @@ package service
 
 import (
 	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
 
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
 	"github.com/REmakerzz/dental-clinic-bot/internal/model"
 	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
 )
 
+// availabilityHorizonDays limits how far ahead free dates are looked for
+const availabilityHorizonDays = 60
+
+// ErrBookingClosed is returned when confirming or cancelling a booking that is
+// already cancelled, completed or missed
+var ErrBookingClosed = errors.New("booking is already closed")
+
 type BookingService struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func NewBookingService(db *sql.DB) *BookingService {
-	return &BookingService{db: db}
+func NewBookingService(db *sql.DB, logger *slog.Logger) *BookingService {
+	return &BookingService{db: db, logger: logger}
 }
 
-func (s *BookingService) SaveBooking(booking *model.Booking) error {
+// SaveBooking saves a new booking for a time after now. A booking with PatientID
+// also creates or updates the patient's profile. If notify is not nil, the
+// notification it builds for the saved booking is put into the outbox in the same
+// transaction, so staff hear about every booking that was actually saved.
+func (s *BookingService) SaveBooking(booking *model.Booking, now time.Time, notify func(*model.Booking) *model.Notification) error {
 	// Validate datetime before saving
-	if err := repository.ValidateDateTime(s.db, booking.DateTime); err != nil {
+	if err := repository.ValidateDateTime(s.db, booking.DateTime, now); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The patient's profile remembers the contacts of their latest booking
+	if booking.PatientID != 0 {
+		if err := repository.SavePatientContacts(tx, booking.PatientID, booking.Name, booking.Phone); err != nil {
+			return err
+		}
+	}
+	if err := repository.SaveBooking(tx, booking); err != nil {
+		return err
+	}
+	if notify != nil {
+		n := notify(booking)
+		n.BookingID = booking.ID
+		if err := repository.EnqueueNotification(tx, n); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
-	return repository.SaveBooking(s.db, booking)
+
+	source := "patient"
+	if booking.AdminID != 0 {
+		source = "admin"
+	}
+	metrics.BookingsCreated.WithLabelValues(source).Inc()
+	s.logger.Info("booking saved", "booking", booking, "source", source)
+	return nil
 }
 
-func (s *BookingService) DeleteBookingByID(id int) error {
-	return repository.DeleteBookingByID(s.db, id)
+// UpdateBooking saves changes made by an admin. A new time goes through the same
+// checks against now and availability as a new booking.
+func (s *BookingService) UpdateBooking(booking *model.Booking, now time.Time) error {
+	current, err := repository.GetBookingByID(s.db, booking.ID)
+	if err != nil {
+		return err
+	}
+
+	if booking.DateTime != current.DateTime {
+		if err := repository.ValidateDateTime(s.db, booking.DateTime, now); err != nil {
+			return err
+		}
+	}
+	if err := repository.UpdateBooking(s.db, booking); err != nil {
+		return err
+	}
+	s.logger.Info("booking updated", "booking", booking)
+	return nil
+}
+
+// SetStatus confirms (StatusConfirmed) or cancels (StatusCancelled) an open booking.
+// Setting the status the booking already has is not an error.
+func (s *BookingService) SetStatus(id int, status string) (*model.Booking, error) {
+	if status != model.StatusConfirmed && status != model.StatusCancelled {
+		return nil, errors.New("unexpected booking status " + status)
+	}
+
+	booking, err := repository.GetBookingByID(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status == status {
+		return booking, nil
+	}
+	if booking.Status != model.StatusNew && booking.Status != model.StatusConfirmed {
+		return nil, ErrBookingClosed
+	}
+
+	if err := repository.SetBookingStatus(s.db, id, status); err != nil {
+		return nil, err
+	}
+	booking.Status = status
+	if status == model.StatusCancelled {
+		metrics.BookingsCancelled.Inc()
+	}
+	s.logger.Info("booking status changed", "booking_id", id, "status", status)
+	return booking, nil
+}
+
+// GetNotification returns the delivered staff notification about a booking or
+// sql.ErrNoRows
+func (s *BookingService) GetNotification(bookingID int) (*model.Notification, error) {
+	return repository.GetBookingNotification(s.db, bookingID)
 }
 
 func (s *BookingService) GetAllBookings() ([]*model.Booking, error) {
 	return repository.GetAllBookings(s.db)
 }
 
+// GetBookingByID returns a booking or sql.ErrNoRows
+func (s *BookingService) GetBookingByID(id int) (*model.Booking, error) {
+	return repository.GetBookingByID(s.db, id)
+}
+
+// SaveListFilter remembers the filter a booking list message was sent with
+func (s *BookingService) SaveListFilter(chatID int64, messageID int, filter model.BookingFilter) error {
+	return repository.SaveListFilter(s.db, chatID, messageID, filter)
+}
+
+// ListFilter returns the filter of a booking list message or sql.ErrNoRows
+// when the list is unknown or too old
+func (s *BookingService) ListFilter(chatID int64, messageID int) (model.BookingFilter, error) {
+	return repository.GetListFilter(s.db, chatID, messageID)
+}
+
+// ListBookings returns the requested page (starting at 0) of bookings matching the filter
+// together with the total number of matches
+func (s *BookingService) ListBookings(filter model.BookingFilter, page, pageSize int) ([]*model.Booking, int, error) {
+	total, err := repository.CountBookings(s.db, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bookings, err := repository.FindBookings(s.db, filter, pageSize, page*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bookings, total, nil
+}
+
+// GetDayBookings returns the agenda of a day (YYYY-MM-DD) without cancelled bookings
+func (s *BookingService) GetDayBookings(date string) ([]*model.Booking, error) {
+	return repository.GetDayBookings(s.db, date)
+}
+
+// ExportBookings returns every booking with an appointment between from and to
+// (YYYY-MM-DD, inclusive); an empty bound leaves that side open
+func (s *BookingService) ExportBookings(from, to string) ([]*model.Booking, error) {
+	if from == "" {
+		from = "0000-01-01"
+	}
+	if to == "" {
+		to = "9999-12-31"
+	}
+	return repository.GetBookingsInRange(s.db, from, to)
+}
+
+// SearchBookings finds bookings by ID, patient name fragment or phone digits
+func (s *BookingService) SearchBookings(query string, limit int) ([]*model.Booking, error) {
+	return repository.SearchBookings(s.db, query, limit)
+}
+
 func (s *BookingService) GetBookingStats() (total int, today int, last7Days int, err error) {
 	return repository.GetBookingStats(s.db)
 }
 
-// GetAvailableTimeSlots returns available time slots for a given date
-func (s *BookingService) GetAvailableTimeSlots(date string) ([]string, error) {
-	return repository.GetAvailableTimeSlots(s.db, date)
+// GetAvailableTimeSlots returns available time slots for a given date that start
+// after now
+func (s *BookingService) GetAvailableTimeSlots(date string, now time.Time) ([]string, error) {
+	availability, err := repository.LoadAvailability(s.db, date, date)
+	if err != nil {
+		return nil, err
+	}
+	return availability.SlotsAfter(date, now), nil
+}
+
+// NearestAvailableDates returns up to n dates (YYYY-MM-DD) with free slots that
+// come after the date after and not before today, looking at most
+// availabilityHorizonDays ahead. The dates are the same for every service and
+// doctor: the clinic has one schedule, every slot takes one booking, services
+// have no duration of their own and a doctor is assigned by staff after booking.
+func (s *BookingService) NearestAvailableDates(after string, n int, now time.Time) ([]string, error) {
+	day, err := time.Parse("2006-01-02", after)
+	if err != nil {
+		return nil, err
+	}
+	day = day.AddDate(0, 0, 1)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if day.Before(today) {
+		day = today
+	}
+
+	last := day.AddDate(0, 0, availabilityHorizonDays-1)
+	availability, err := repository.LoadAvailability(s.db, day.Format("2006-01-02"), last.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []string
+	for ; !day.After(last) && len(dates) < n; day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if len(availability.SlotsAfter(date, now)) > 0 {
+			dates = append(dates, date)
+		}
+	}
+	return dates, nil
 }
 
 // IsDateTimeAvailable checks if the given datetime is available for booking
@@ -45,7 +236,8 @@ func (s *BookingService) IsDateTimeAvailable(datetime string) (bool, error) {
 	return repository.IsDateTimeAvailable(s.db, datetime)
 }
 
-// ValidateDateTime checks if the datetime is in correct format and within working hours
-func (s *BookingService) ValidateDateTime(datetime string) error {
-	return repository.ValidateDateTime(s.db, datetime)
+// ValidateDateTime checks if the datetime is in correct format, still ahead of now
+// and within working hours
+func (s *BookingService) ValidateDateTime(datetime string, now time.Time) error {
+	return repository.ValidateDateTime(s.db, datetime, now)
 }
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+// feedHistoryDays — сколько дней прошедших приёмов остаётся в календаре врача
+const feedHistoryDays = 30
+
+var (
+	// ErrOtherDoctor is returned when a doctor acts on another doctor's appointment
+	ErrOtherDoctor = errors.New("appointment belongs to another doctor")
+	// ErrVisitNotStarted is returned when marking the outcome of a future appointment
+	ErrVisitNotStarted = errors.New("appointment has not started yet")
+	// ErrVisitCancelled is returned when marking the outcome of a cancelled appointment
+	ErrVisitCancelled = errors.New("appointment is cancelled")
+)
+
+type DoctorService struct {
+	db *sql.DB
+}
+
+func NewDoctorService(db *sql.DB) *DoctorService {
+	return &DoctorService{db: db}
+}
+
+func (s *DoctorService) CreateDoctor(name string) (*model.Doctor, error) {
+	return repository.CreateDoctor(s.db, name)
+}
+
+func (s *DoctorService) GetDoctors() ([]*model.Doctor, error) {
+	return repository.GetDoctors(s.db)
+}
+
+// AssignDoctor sets the doctor of a booking; doctorID 0 removes the assignment
+func (s *DoctorService) AssignDoctor(bookingID int, doctorID int64) error {
+	return repository.AssignDoctor(s.db, bookingID, doctorID)
+}
+
+// GetFeed returns the doctor owning the feed token and the appointments to publish in the feed
+func (s *DoctorService) GetFeed(token string) (*model.Doctor, []*model.Booking, error) {
+	doctor, err := repository.GetDoctorByFeedToken(s.db, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	from := time.Now().AddDate(0, 0, -feedHistoryDays).Format("2006-01-02")
+	bookings, err := repository.GetDoctorBookings(s.db, doctor.ID, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doctor, bookings, nil
+}
+
+// GetDoctorByName returns the active doctor with the given name, ignoring case, or sql.ErrNoRows
+func (s *DoctorService) GetDoctorByName(name string) (*model.Doctor, error) {
+	return repository.GetDoctorByName(s.db, name)
+}
+
+// GetSchedule returns the doctor's appointments between from and to (YYYY-MM-DD,
+// inclusive) that are not cancelled
+func (s *DoctorService) GetSchedule(doctorID int64, from, to string) ([]*model.Booking, error) {
+	return repository.GetDoctorSchedule(s.db, doctorID, from, to)
+}
+
+// GetVisit returns an appointment of the doctor; ErrOtherDoctor if it is not theirs
+func (s *DoctorService) GetVisit(doctorID int64, bookingID int) (*model.Booking, error) {
+	booking, err := repository.GetBookingByID(s.db, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.DoctorID != doctorID {
+		return nil, ErrOtherDoctor
+	}
+	return booking, nil
+}
+
+// MarkVisit records the outcome of the doctor's appointment: StatusCompleted or
+// StatusNoShow. The appointment must have started by now.
+func (s *DoctorService) MarkVisit(doctorID int64, bookingID int, status string, now time.Time) (*model.Booking, error) {
+	if status != model.StatusCompleted && status != model.StatusNoShow {
+		return nil, errors.New("unexpected visit outcome " + status)
+	}
+
+	booking, err := s.GetVisit(doctorID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking.Status == model.StatusCancelled {
+		return nil, ErrVisitCancelled
+	}
+	if booking.DateTime > now.Format("2006-01-02 15:04") {
+		return nil, ErrVisitNotStarted
+	}
+
+	if err := repository.SetBookingStatus(s.db, bookingID, status); err != nil {
+		return nil, err
+	}
+	booking.Status = status
+	return booking, nil
+}
+
+// SetVisitNote saves the doctor's note on their appointment
+func (s *DoctorService) SetVisitNote(doctorID int64, bookingID int, note string) (*model.Booking, error) {
+	booking, err := s.GetVisit(doctorID, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if err := repository.SetVisitNote(s.db, bookingID, note); err != nil {
+		return nil, err
+	}
+	booking.VisitNote = note
+	return booking, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+// ErrInvalidBirthDate is returned for a birth date that is not YYYY-MM-DD in the past
+var ErrInvalidBirthDate = errors.New("invalid birth date")
+
+// PatientService keeps the profiles of patients who book through the bot
+type PatientService struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewPatientService(db *sql.DB, logger *slog.Logger) *PatientService {
+	return &PatientService{db: db, logger: logger}
+}
+
+// GetPatient returns the profile of a patient or sql.ErrNoRows
+func (s *PatientService) GetPatient(userID int64) (*model.Patient, error) {
+	return repository.GetPatient(s.db, userID)
+}
+
+// SetBirthDate saves the patient's birth date; an empty date clears it
+func (s *PatientService) SetBirthDate(userID int64, date string) (*model.Patient, error) {
+	if date != "" {
+		birth, err := time.Parse("2006-01-02", date)
+		if err != nil || !birth.Before(time.Now()) {
+			return nil, ErrInvalidBirthDate
+		}
+	}
+	return s.update(userID, func(p *model.Patient) { p.BirthDate = date })
+}
+
+// SetPreferences saves the patient's wishes; empty text clears them
+func (s *PatientService) SetPreferences(userID int64, text string) (*model.Patient, error) {
+	return s.update(userID, func(p *model.Patient) { p.Preferences = text })
+}
+
+// GetPatientCard finds a patient by Telegram ID or phone number and collects their
+// bookings. A number that is not a patient's Telegram ID is taken as a phone, which
+// also finds patients who were only ever booked by staff. Returns sql.ErrNoRows if
+// nothing is found.
+func (s *PatientService) GetPatientCard(query string) (*model.PatientCard, error) {
+	query = strings.TrimSpace(query)
+	card := &model.PatientCard{}
+
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		card.Patient, err = repository.GetPatient(s.db, id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	if card.Patient == nil {
+		var err error
+		card.Patient, err = repository.GetPatientByPhone(s.db, query)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	var userID int64
+	phone := query
+	if card.Patient != nil {
+		userID, phone = card.Patient.UserID, card.Patient.Phone
+		card.Name, card.Phone = card.Patient.Name, card.Patient.Phone
+	}
+
+	var err error
+	card.Bookings, err = repository.GetPatientBookings(s.db, userID, phone)
+	if err != nil {
+		return nil, err
+	}
+	if card.Patient == nil {
+		if len(card.Bookings) == 0 {
+			return nil, sql.ErrNoRows
+		}
+		card.Name, card.Phone = card.Bookings[0].Name, card.Bookings[0].Phone
+	}
+	return card, nil
+}
+
+func (s *PatientService) update(userID int64, change func(*model.Patient)) (*model.Patient, error) {
+	patient, err := repository.GetPatient(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	change(patient)
+	if err := repository.UpdatePatient(s.db, patient); err != nil {
+		return nil, err
+	}
+	s.logger.Info("patient profile updated", "patient", patient)
+	return patient, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+// createdAtLayout — формат CURRENT_TIMESTAMP в SQLite, время в UTC
+const createdAtLayout = "2006-01-02 15:04:05"
+
+// GetPeriodStats считает статистику заявок с приёмом в периоде from..to (YYYY-MM-DD включительно)
+func (s *BookingService) GetPeriodStats(from, to string) (*model.BookingStats, error) {
+	bookings, err := repository.GetBookingsInRange(s.db, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return computeStats(from, to, bookings), nil
+}
+
+func computeStats(from, to string, bookings []*model.Booking) *model.BookingStats {
+	stats := &model.BookingStats{From: from, To: to, Total: len(bookings)}
+	byService := map[string]int{}
+	byDoctor := map[string]int{}
+	byDate := map[string]int{}
+	var leadTimes []time.Duration
+
+	for _, b := range bookings {
+		switch b.Status {
+		case model.StatusCancelled:
+			stats.Cancelled++
+		case model.StatusNoShow:
+			stats.NoShow++
+		case model.StatusCompleted:
+			stats.Completed++
+		}
+
+		byService[b.Service]++
+		byDoctor[b.Doctor]++
+
+		visit, err := time.ParseInLocation("2006-01-02 15:04", b.DateTime, time.Local)
+		if err != nil {
+			continue
+		}
+		stats.ByWeekday[visit.Weekday()]++
+		stats.ByHour[visit.Hour()]++
+		byDate[visit.Format("2006-01-02")]++
+		if b.Status != model.StatusCancelled {
+			stats.Load[visit.Weekday()][visit.Hour()]++
+		}
+
+		if created, err := time.Parse(createdAtLayout, b.CreatedAt); err == nil && visit.After(created) {
+			leadTimes = append(leadTimes, visit.Sub(created))
+		}
+	}
+
+	stats.ByService = sortedItems(byService)
+	stats.ByDoctor = sortedItems(byDoctor)
+
+	start, errFrom := time.Parse("2006-01-02", from)
+	end, errTo := time.Parse("2006-01-02", to)
+	if errFrom == nil && errTo == nil {
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			stats.ByDate = append(stats.ByDate, model.StatItem{Name: date, Count: byDate[date]})
+		}
+	}
+
+	if len(leadTimes) > 0 {
+		var sum time.Duration
+		for _, d := range leadTimes {
+			sum += d
+		}
+		sort.Slice(leadTimes, func(i, j int) bool { return leadTimes[i] < leadTimes[j] })
+		stats.LeadTimeAvg = sum / time.Duration(len(leadTimes))
+		stats.LeadTimeMedian = leadTimes[len(leadTimes)/2]
+	}
+
+	return stats
+}
+
+func sortedItems(counts map[string]int) []model.StatItem {
+	items := make([]model.StatItem, 0, len(counts))
+	for name, count := range counts {
+		items = append(items, model.StatItem{Name: name, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
+	"github.com/REmakerzz/dental-clinic-bot/internal/model"
+	"github.com/REmakerzz/dental-clinic-bot/internal/repository"
+)
+
+const (
+	// waitlistPerPatient limits the entries one patient can wait on at a time
+	waitlistPerPatient = 3
+	// waitlistMaxDays limits the length of the date range of an entry
+	waitlistMaxDays = 14
+)
+
+var (
+	// ErrWaitlistFull is returned when a patient already waits on too many entries
+	ErrWaitlistFull = errors.New("too many waitlist entries")
+	// ErrInvalidWaitRange is returned for a date range in the past, reversed or too long
+	ErrInvalidWaitRange = errors.New("invalid waitlist date range")
+	// ErrOfferClosed is returned when an offer was already claimed, declined or has expired
+	ErrOfferClosed = errors.New("offer is no longer open")
+)
+
+// WaitlistService keeps patients waiting for a free slot and offers them the slots
+// that become free, one patient at a time
+type WaitlistService struct {
+	db       *sql.DB
+	claimTTL time.Duration
+	logger   *slog.Logger
+}
+
+// NewWaitlistService creates the service; an offered slot waits claimTTL for the
+// patient to claim it before it goes to the next one
+func NewWaitlistService(db *sql.DB, claimTTL time.Duration, logger *slog.Logger) *WaitlistService {
+	return &WaitlistService{db: db, claimTTL: claimTTL, logger: logger}
+}
+
+// ClaimTTL returns how long a patient has to claim an offered slot
+func (s *WaitlistService) ClaimTTL() time.Duration {
+	return s.claimTTL
+}
+
+// Join puts a patient on the waitlist for the dates in the entry
+func (s *WaitlistService) Join(entry *model.WaitlistEntry, now time.Time) error {
+	from, err := time.Parse("2006-01-02", entry.DateFrom)
+	if err != nil {
+		return ErrInvalidWaitRange
+	}
+	to, err := time.Parse("2006-01-02", entry.DateTo)
+	if err != nil || to.Before(from) || to.Sub(from) >= waitlistMaxDays*24*time.Hour ||
+		entry.DateTo < now.Format("2006-01-02") {
+		return ErrInvalidWaitRange
+	}
+	if !model.IsValidDayPart(entry.DayPart) {
+		return errors.New("unknown day part " + entry.DayPart)
+	}
+
+	waiting, err := repository.CountWaitingEntries(s.db, entry.UserID)
+	if err != nil {
+		return err
+	}
+	if waiting >= waitlistPerPatient {
+		return ErrWaitlistFull
+	}
+
+	if err := repository.AddWaitlistEntry(s.db, entry); err != nil {
+		return err
+	}
+	s.logger.Info("patient joined waitlist", "entry_id", entry.ID, "user_id", entry.UserID,
+		"from", entry.DateFrom, "to", entry.DateTo, "day_part", entry.DayPart)
+	return nil
+}
+
+// offerKey identifies a slot offered to an entry
+type offerKey struct {
+	entryID  int64
+	datetime string
+}
+
+// OfferFreedSlots expires unanswered offers and entries whose dates have passed,
+// then offers every waiting patient without an open offer the first free slot that
+// suits them. Patients are served in the order they joined; a slot is offered to
+// one patient at a time and never twice to the same patient. An open offer holds
+// its slot: other bookers do not see it until it is claimed, declined or expires.
+// Returns the new offers, which the caller sends to the patients.
+func (s *WaitlistService) OfferFreedSlots(now time.Time) ([]*model.SlotOffer, error) {
+	if _, err := repository.ExpireWaitlist(s.db, now.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+	expired, err := repository.ExpireOffers(s.db, now)
+	if err != nil {
+		return nil, err
+	}
+	metrics.WaitlistOffers.WithLabelValues(model.OfferExpired).Add(float64(expired))
+
+	entries, err := repository.GetWaitingEntries(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	previous, err := repository.GetWaitingOffers(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool) // slots under an open offer
+	busy := make(map[int64]bool)   // entries with an open offer
+	offered := make(map[offerKey]bool)
+	for _, o := range previous {
+		offered[offerKey{o.EntryID, o.DateTime}] = true
+		if o.Status == model.OfferPending {
+			taken[o.DateTime] = true
+			busy[o.EntryID] = true
+		}
+	}
+
+	availability, err := loadWaitlistAvailability(s.db, entries, now)
+	if err != nil {
+		return nil, err
+	}
+	var offers []*model.SlotOffer
+	for _, entry := range entries {
+		if busy[entry.ID] {
+			continue
+		}
+		slot, err := findSlot(entry, now, availability, func(slot string) bool {
+			return !taken[slot] && !offered[offerKey{entry.ID, slot}]
+		})
+		if err != nil {
+			return offers, err
+		}
+		if slot == "" {
+			continue
+		}
+
+		offer, err := repository.CreateOffer(s.db, entry, slot, now.Add(s.claimTTL))
+		if err != nil {
+			return offers, err
+		}
+		taken[slot] = true
+		offers = append(offers, offer)
+		metrics.WaitlistOffers.WithLabelValues("offered").Inc()
+		s.logger.Info("slot offered", "offer_id", offer.ID, "entry_id", entry.ID, "datetime", slot)
+	}
+	return offers, nil
+}
+
+// loadWaitlistAvailability loads free slots for all dates the entries wait for,
+// starting from today
+func loadWaitlistAvailability(db *sql.DB, entries []*model.WaitlistEntry, now time.Time) (*repository.Availability, error) {
+	from := now.Format("2006-01-02")
+	to := from
+	for _, entry := range entries {
+		to = max(to, entry.DateTo)
+	}
+	return repository.LoadAvailability(db, from, to)
+}
+
+// findSlot returns the first free future slot in the entry's dates and time of
+// day that open accepts, or ""
+func findSlot(entry *model.WaitlistEntry, now time.Time, availability *repository.Availability,
+	open func(slot string) bool) (string, error) {
+	from, err := time.Parse("2006-01-02", entry.DateFrom)
+	if err != nil {
+		return "", err
+	}
+	to, err := time.Parse("2006-01-02", entry.DateTo)
+	if err != nil {
+		return "", err
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		for _, slot := range availability.SlotsAfter(day.Format("2006-01-02"), now) {
+			if model.InDayPart(entry.DayPart, slot[11:]) && open(slot) {
+				return slot, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Claim books the offered slot for the patient userID. If notify is not nil, the
+// notification it builds is put into the outbox with the booking. Returns
+// sql.ErrNoRows for an offer that does not exist or belongs to another patient,
+// ErrOfferClosed if it is no longer open and repository.ErrSlotUnavailable if the
+// slot turned out to be taken.
+func (s *WaitlistService) Claim(offerID, userID int64, now time.Time, notify func(*model.Booking) *model.Notification) (*model.Booking, error) {
+	offer, err := repository.GetOffer(s.db, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Entry.UserID != userID {
+		return nil, sql.ErrNoRows
+	}
+	if offer.Status != model.OfferPending {
+		return nil, ErrOfferClosed
+	}
+
+	// The offer holds the slot from other bookers, but it may still have been
+	// taken before the offer was made, e.g. by an admin
+	availability, err := repository.LoadAvailability(s.db, offer.DateTime[:10], offer.DateTime[:10])
+	if err != nil {
+		return nil, err
+	}
+	availability.Release(offer.DateTime)
+	if !availability.IsAvailable(offer.DateTime) {
+		if err := repository.CloseOffer(s.db, offerID, model.OfferExpired, now); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, repository.ErrSlotUnavailable
+	}
+
+	booking := &model.Booking{
+		Name:      offer.Entry.Name,
+		Phone:     offer.Entry.Phone,
+		Service:   offer.Entry.Service,
+		DateTime:  offer.DateTime,
+		PatientID: offer.Entry.UserID,
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := repository.CloseOffer(tx, offerID, model.OfferClaimed, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOfferClosed
+		}
+		return nil, err
+	}
+	if err := repository.SavePatientContacts(tx, booking.PatientID, booking.Name, booking.Phone); err != nil {
+		return nil, err
+	}
+	if err := repository.SaveBooking(tx, booking); err != nil {
+		return nil, err
+	}
+	if err := repository.SetWaitlistBooked(tx, offer.EntryID, booking.ID); err != nil {
+		return nil, err
+	}
+	if notify != nil {
+		n := notify(booking)
+		n.BookingID = booking.ID
+		if err := repository.EnqueueNotification(tx, n); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	metrics.BookingsCreated.WithLabelValues("waitlist").Inc()
+	metrics.WaitlistOffers.WithLabelValues(model.OfferClaimed).Inc()
+	s.logger.Info("booking saved", "booking", booking, "source", "waitlist", "offer_id", offerID)
+	return booking, nil
+}
+
+// Withdraw closes an offer that could not be delivered to the patient, so that its
+// slot is no longer held. The slot is not offered to the same patient again.
+func (s *WaitlistService) Withdraw(offerID int64, now time.Time) error {
+	if err := repository.CloseOffer(s.db, offerID, model.OfferExpired, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOfferClosed
+		}
+		return err
+	}
+	metrics.WaitlistOffers.WithLabelValues(model.OfferExpired).Inc()
+	return nil
+}
+
+// Decline gives up an offered slot; the patient stays on the waitlist for other slots
+func (s *WaitlistService) Decline(offerID, userID int64, now time.Time) error {
+	offer, err := repository.GetOffer(s.db, offerID)
+	if err != nil {
+		return err
+	}
+	if offer.Entry.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if err := repository.CloseOffer(s.db, offerID, model.OfferDeclined, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOfferClosed
+		}
+		return err
+	}
+	metrics.WaitlistOffers.WithLabelValues(model.OfferDeclined).Inc()
+	return nil
+}
@@ -0,0 +1,158 @@
+package telegram
+
+import (
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/metrics"
+)
+
+const (
+	// maxAttempts — сколько раз пробовать запрос, включая первый
+	maxAttempts = 4
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 8 * time.Second
+	// maxRetryAfter — дольше ждать по flood wait нет смысла даже в фоне
+	maxRetryAfter = 30 * time.Second
+	// Ответ на обновление может ждать своей очереди по ограничению частоты не
+	// дольше maxInteractiveWait, а повторяется один раз и после retry_after не
+	// дольше maxInteractiveRetryAfter: пока он ждёт, не обрабатываются
+	// обновления остальных
+	maxInteractiveWait       = time.Second
+	interactiveAttempts      = 2
+	maxInteractiveRetryAfter = 5 * time.Second
+)
+
+// ErrRateLimited возвращает клиент для ответов, если отправке пришлось бы ждать
+// дольше maxInteractiveWait
+var ErrRateLimited = errors.New("telegram: rate limit reached, request not sent")
+
+// Client отправляет запросы через next с соблюдением ограничений частоты Bot API.
+// Ответ 429 повторяется через retry_after, ошибки сети и 5xx — с растущей
+// паузой; остальные ошибки и исчерпанные попытки записываются в лог.
+//
+// Так работают фоновые отправки вроде outbox. Ответы на обновления идут через
+// Interactive: обновления обрабатываются по одному, и долгая пауза в одном
+// ответе задержала бы всех.
+type Client struct {
+	next        Sender
+	logger      *slog.Logger
+	limiter     *limiter
+	sleep       func(time.Duration)
+	interactive bool
+}
+
+func NewClient(next Sender, logger *slog.Logger) *Client {
+	return &Client{
+		next:    next,
+		logger:  logger,
+		limiter: newLimiter(),
+		sleep:   time.Sleep,
+	}
+}
+
+// Interactive возвращает клиент для ответов на обновления с общим ограничением
+// частоты. Он повторяет запрос один раз и ждёт только короткий retry_after, а
+// если до отправки пришлось бы ждать дольше maxInteractiveWait, сразу
+// возвращает ErrRateLimited.
+func (c *Client) Interactive() *Client {
+	interactive := *c
+	interactive.interactive = true
+	return &interactive
+}
+
+func (c *Client) Send(ch tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var msg tgbotapi.Message
+	err := c.do(ch, func() (err error) {
+		msg, err = c.next.Send(ch)
+		return err
+	})
+	return msg, err
+}
+
+func (c *Client) Request(ch tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := c.do(ch, func() (err error) {
+		resp, err = c.next.Request(ch)
+		return err
+	})
+	return resp, err
+}
+
+func (c *Client) do(ch tgbotapi.Chattable, call func() error) error {
+	out := Describe(ch)
+	maxWait, attempts, retryAfterLimit := time.Duration(0), maxAttempts, maxRetryAfter
+	if c.interactive {
+		maxWait, attempts, retryAfterLimit = maxInteractiveWait, interactiveAttempts, maxInteractiveRetryAfter
+	}
+	for attempt := 1; ; attempt++ {
+		wait, ok := c.limiter.reserve(out, time.Now(), maxWait)
+		if !ok {
+			c.logger.Warn("telegram request dropped by rate limit", "method", out.Method, "chat_id", out.ChatID,
+				"wait", wait)
+			metrics.TelegramSendFailures.WithLabelValues(out.Method).Inc()
+			return ErrRateLimited
+		}
+		if wait > 0 {
+			c.sleep(wait)
+		}
+
+		// Ошибка сети содержит URL запроса с токеном бота
+		err := RedactToken(call())
+		if err == nil {
+			return nil
+		}
+
+		wait, retry := retryDelay(err, attempt, retryAfterLimit)
+		if !retry || attempt == attempts {
+			c.logger.Error("telegram request failed", "method", out.Method, "chat_id", out.ChatID,
+				"attempts", attempt, "error", err)
+			metrics.TelegramSendFailures.WithLabelValues(out.Method).Inc()
+			return err
+		}
+
+		c.logger.Warn("telegram request failed, retrying", "method", out.Method, "chat_id", out.ChatID,
+			"attempt", attempt, "retry_in", wait, "error", err)
+		metrics.TelegramSendRetries.WithLabelValues(out.Method).Inc()
+		c.sleep(wait)
+	}
+}
+
+// retryDelay решает, стоит ли повторять запрос после err, и через сколько;
+// retry_after дольше maxRetryAfter не ждётся
+func retryDelay(err error, attempt int, maxRetryAfter time.Duration) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		if apiErr.RetryAfter > 0 {
+			wait := time.Duration(apiErr.RetryAfter) * time.Second
+			return wait, wait <= maxRetryAfter
+		}
+		// 4xx — ошибка запроса или бот заблокирован, повтор не поможет
+		return backoff(attempt), apiErr.Code >= 500
+	}
+
+	// Запрос не дошёл до Telegram или ответ потерялся
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return backoff(attempt), true
+	}
+	return 0, false
+}
+
+// Permanent сообщает, что запрос отклонён Telegram и повтор его не исправит:
+// неверный чат, бот удалён из группы или заблокирован пользователем
+func Permanent(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.RetryAfter == 0 && apiErr.Code >= 400 && apiErr.Code < 500
+}
+
+// backoff — пауза перед повтором: удваивается с каждой попыткой, со случайным разбросом
+func backoff(attempt int) time.Duration {
+	d := min(baseBackoff<<(attempt-1), maxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
@@ -0,0 +1,160 @@
+package telegram_test
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegramtest"
+)
+
+func newTestClient(next telegram.Sender) (*telegram.Client, *[]time.Duration) {
+	var sleeps []time.Duration
+	c := telegram.NewClient(next, slog.New(slog.DiscardHandler))
+	telegram.SetSleep(c, func(d time.Duration) { sleeps = append(sleeps, d) })
+	return c, &sleeps
+}
+
+func TestClientRetries(t *testing.T) {
+	floodWait := &tgbotapi.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}
+	longFloodWait := &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 600}}
+	blocked := &tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+	serverErr := &tgbotapi.Error{Code: 502, Message: "Bad Gateway"}
+	netErr := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: errors.New("connection reset")}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"ok", nil, nil, 1},
+		{"flood wait", []error{floodWait}, nil, 2},
+		{"flood wait too long", []error{longFloodWait}, longFloodWait, 1},
+		{"blocked by user", []error{blocked}, blocked, 1},
+		{"server error", []error{serverErr, serverErr}, nil, 3},
+		{"network error", []error{netErr}, nil, 2},
+		{"retries exhausted", []error{netErr, netErr, netErr, netErr, netErr}, netErr, telegram.MaxAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &telegramtest.FlakySender{Errs: tt.errs}
+			c, sleeps := newTestClient(next)
+
+			_, err := c.Send(tgbotapi.NewMessage(1, "hi"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if next.Calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", next.Calls, tt.wantCalls)
+			}
+			if tt.name == "flood wait" && (len(*sleeps) == 0 || (*sleeps)[0] != 3*time.Second) {
+				t.Errorf("did not honour retry_after, slept %v", *sleeps)
+			}
+		})
+	}
+}
+
+func TestInteractiveClient(t *testing.T) {
+	floodWait := &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}
+	longFloodWait := &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 20}}
+	netErr := &url.Error{Op: "Post", URL: "https://api.telegram.org", Err: errors.New("connection reset")}
+
+	// Ответ повторяется один раз и ждёт только короткий flood wait
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"flood wait", []error{floodWait}, nil, 2},
+		{"flood wait too long", []error{longFloodWait}, longFloodWait, 1},
+		{"network error", []error{netErr}, nil, 2},
+		{"retries exhausted", []error{netErr, netErr}, netErr, telegram.InteractiveAttempts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &telegramtest.FlakySender{Errs: tt.errs}
+			c, sleeps := newTestClient(next)
+			if _, err := c.Interactive().Send(tgbotapi.NewMessage(1, "hi")); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if next.Calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", next.Calls, tt.wantCalls)
+			}
+			if tt.name == "flood wait" && (len(*sleeps) != 1 || (*sleeps)[0] != 3*time.Second) {
+				t.Errorf("did not honour retry_after, slept %v", *sleeps)
+			}
+		})
+	}
+
+	// Группа сверх лимита: ответ не ждёт, а фоновая отправка ждёт своей очереди
+	next := &telegramtest.FlakySender{}
+	c, sleeps := newTestClient(next)
+	interactive := c.Interactive()
+	for range 5 {
+		if _, err := interactive.Send(tgbotapi.NewMessage(-100, "hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := interactive.Send(tgbotapi.NewMessage(-100, "hi")); !errors.Is(err, telegram.ErrRateLimited) {
+		t.Errorf("got %v, want ErrRateLimited", err)
+	}
+	if _, err := c.Send(tgbotapi.NewMessage(-100, "hi")); err != nil || len(*sleeps) != 1 {
+		t.Errorf("background send: %v, slept %v", err, *sleeps)
+	}
+	if next.Calls != 6 {
+		t.Errorf("sent %d messages, want 6", next.Calls)
+	}
+}
+
+func TestRedactToken(t *testing.T) {
+	netErr := &url.Error{
+		Op:  "Post",
+		URL: "https://api.telegram.org/bot123456789:AAH-secret_Token/getMe",
+		Err: errors.New("dial tcp: i/o timeout"),
+	}
+
+	err := telegram.RedactToken(netErr)
+	if strings.Contains(err.Error(), "AAH-secret_Token") || !strings.Contains(err.Error(), "bot<token>/getMe") {
+		t.Errorf("token not redacted: %q", err)
+	}
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		t.Error("redacted error does not unwrap to *url.Error")
+	}
+
+	if apiErr := (&tgbotapi.Error{Code: 403, Message: "Forbidden"}); telegram.RedactToken(apiErr) != apiErr {
+		t.Error("error without a token is wrapped")
+	}
+	if telegram.RedactToken(nil) != nil {
+		t.Error("nil error is wrapped")
+	}
+}
+
+func TestClientRedactsToken(t *testing.T) {
+	netErr := &url.Error{Op: "Post", URL: "https://api.telegram.org/bot123456789:AAH-secret_Token/sendMessage", Err: errors.New("connection reset")}
+	var logs strings.Builder
+	c := telegram.NewClient(&telegramtest.FlakySender{Errs: []error{netErr, netErr, netErr, netErr}}, slog.New(slog.NewTextHandler(&logs, nil)))
+	telegram.SetSleep(c, func(time.Duration) {})
+
+	_, err := c.Send(tgbotapi.NewMessage(1, "hi"))
+	if err == nil || strings.Contains(err.Error(), "AAH-secret_Token") {
+		t.Errorf("returned error %v", err)
+	}
+	if strings.Contains(logs.String(), "AAH-secret_Token") {
+		t.Errorf("token in the log:\n%s", logs.String())
+	}
+
+	logs.Reset()
+	telegram.NewBotLogger(slog.New(slog.NewTextHandler(&logs, nil))).Println(netErr)
+	if strings.Contains(logs.String(), "AAH-secret_Token") || !strings.Contains(logs.String(), "bot<token>") {
+		t.Errorf("library log not redacted:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,14 @@
+package telegram
+
+import "time"
+
+// Доступ к внутренностям Client для внешних тестов пакета
+
+const (
+	MaxAttempts         = maxAttempts
+	InteractiveAttempts = interactiveAttempts
+)
+
+func SetSleep(c *Client, sleep func(time.Duration)) {
+	c.sleep = sleep
+}
@@ -0,0 +1,156 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// memberSender отвечает на getChatMember статусом из statuses и запоминает отправленное
+type memberSender struct {
+	statuses map[int64]string
+	err      error
+	requests int
+	sent     []tgbotapi.Chattable
+}
+
+func (s *memberSender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.sent = append(s.sent, c)
+	return tgbotapi.Message{MessageID: 1}, nil
+}
+
+func (s *memberSender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	cfg, ok := c.(tgbotapi.GetChatMemberConfig)
+	if !ok {
+		s.sent = append(s.sent, c)
+		return &tgbotapi.APIResponse{Ok: true}, nil
+	}
+	s.requests++
+	if s.err != nil {
+		return nil, s.err
+	}
+	status, ok := s.statuses[cfg.UserID]
+	if !ok {
+		return nil, &tgbotapi.Error{Code: 400, Message: "Bad Request: user not found"}
+	}
+	result, _ := json.Marshal(tgbotapi.ChatMember{Status: status})
+	return &tgbotapi.APIResponse{Ok: true, Result: result}, nil
+}
+
+func TestMembers(t *testing.T) {
+	sender := &memberSender{statuses: map[int64]string{1: "administrator", 2: "member", 3: "left", 4: "kicked"}}
+	m := NewMembers(sender, time.Minute)
+	now := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	for userID, want := range map[int64]bool{1: true, 2: true, 3: false, 4: false, 5: false} {
+		if got, err := m.IsMember(-100, userID); err != nil || got != want {
+			t.Errorf("user %d: got %v, %v; want %v", userID, got, err, want)
+		}
+	}
+
+	// Ответы запомнены
+	sender.requests = 0
+	sender.statuses[2] = "left"
+	if got, _ := m.IsMember(-100, 2); !got || sender.requests != 0 {
+		t.Errorf("cached answer: got %v after %d requests", got, sender.requests)
+	}
+
+	// Forget и истечение ttl заставляют спросить заново
+	m.Forget(-100, 2)
+	if got, _ := m.IsMember(-100, 2); got || sender.requests != 1 {
+		t.Errorf("after Forget: got %v after %d requests", got, sender.requests)
+	}
+	sender.statuses[3] = "member"
+	now = now.Add(2 * time.Minute)
+	if got, _ := m.IsMember(-100, 3); !got || sender.requests != 2 {
+		t.Errorf("after ttl: got %v after %d requests", got, sender.requests)
+	}
+
+	// Временная ошибка возвращается и не кэшируется
+	sender.err = errors.New("connection reset")
+	m.Forget(-100, 1)
+	if _, err := m.IsMember(-100, 1); err == nil {
+		t.Error("expected an error")
+	}
+	sender.err = nil
+	if got, err := m.IsMember(-100, 1); !got || err != nil {
+		t.Errorf("after error: got %v, %v", got, err)
+	}
+}
+
+func TestIsChatMember(t *testing.T) {
+	tests := []struct {
+		member tgbotapi.ChatMember
+		want   bool
+	}{
+		{tgbotapi.ChatMember{Status: "creator"}, true},
+		{tgbotapi.ChatMember{Status: "restricted", IsMember: true}, true},
+		{tgbotapi.ChatMember{Status: "restricted"}, false},
+		{tgbotapi.ChatMember{Status: "left"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsChatMember(tt.member); got != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.member, got, tt.want)
+		}
+	}
+}
+
+func TestThreads(t *testing.T) {
+	sender := &memberSender{}
+	threads := NewThreads(sender, -100, 7)
+
+	replyTo := func() int {
+		t.Helper()
+		switch c := sender.sent[len(sender.sent)-1].(type) {
+		case tgbotapi.MessageConfig:
+			return c.ReplyToMessageID
+		case tgbotapi.DocumentConfig:
+			return c.ReplyToMessageID
+		default:
+			t.Fatalf("unexpected %T", c)
+			return 0
+		}
+	}
+
+	// Вне обработки обновления — в тему по умолчанию
+	threads.Send(tgbotapi.NewMessage(-100, "digest"))
+	if got := replyTo(); got != 7 {
+		t.Errorf("default thread: reply to %d, want 7", got)
+	}
+
+	done := threads.ReplyTo(42)
+	threads.Send(tgbotapi.NewMessage(-100, "answer"))
+	if got := replyTo(); got != 42 {
+		t.Errorf("while handling: reply to %d, want 42", got)
+	}
+	threads.Send(tgbotapi.NewDocument(-100, tgbotapi.FileBytes{Name: "a.csv"}))
+	if got := replyTo(); got != 42 {
+		t.Errorf("document: reply to %d, want 42", got)
+	}
+	explicit := tgbotapi.NewMessage(-100, "explicit")
+	explicit.ReplyToMessageID = 5
+	threads.Send(explicit)
+	if got := replyTo(); got != 5 {
+		t.Errorf("explicit reply overwritten with %d", got)
+	}
+	threads.Send(tgbotapi.NewMessage(42, "private"))
+	if got := replyTo(); got != 0 {
+		t.Errorf("private chat message replies to %d", got)
+	}
+	done()
+
+	threads.Send(tgbotapi.NewMessage(-100, "after"))
+	if got := replyTo(); got != 7 {
+		t.Errorf("after done: reply to %d, want 7", got)
+	}
+
+	// Без темы по умолчанию сообщения идут как есть
+	NewThreads(sender, -100, 0).Send(tgbotapi.NewMessage(-100, "plain"))
+	if got := replyTo(); got != 0 {
+		t.Errorf("no thread: reply to %d", got)
+	}
+}
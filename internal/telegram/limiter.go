@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ограничения Bot API: не больше 30 сообщений в секунду всего, около одного в
+// секунду в личный чат и не больше 20 в минуту в группу. Небольшие всплески
+// (подтверждение + файл + меню) Telegram допускает.
+const (
+	globalRate   = 30.0
+	globalBurst  = 30
+	privateRate  = 1.0
+	privateBurst = 3
+	groupRate    = 20.0 / 60
+	groupBurst   = 5
+
+	// maxChatBuckets — после стольких чатов неиспользуемые счётчики удаляются
+	maxChatBuckets = 10000
+)
+
+// bucket — ведро токенов; take может уйти в минус, тогда
+// вызывающий ждёт, пока долг не покроется
+type bucket struct {
+	rate   float64 // токенов в секунду
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+// take забирает токен и возвращает, сколько ждать до его появления
+func (b *bucket) take(now time.Time) time.Duration {
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle сообщает, что ведро уже наполнилось и его можно забыть
+func (b *bucket) idle(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+// limiter распределяет отправку сообщений во времени по правилам Bot API
+type limiter struct {
+	mu     sync.Mutex
+	global *bucket
+	chats  map[int64]*bucket
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		global: newBucket(globalRate, globalBurst),
+		chats:  make(map[int64]*bucket),
+	}
+}
+
+// reserve учитывает запрос и возвращает, сколько ждать перед отправкой.
+// Если maxWait больше нуля и ждать пришлось бы дольше, запрос не учитывается
+// и ok равно false. Ответы на нажатия и удаление сообщений не ограничиваются, а
+// правка уже отправленного сообщения не считается новым сообщением в чат и
+// учитывается только в общем лимите.
+func (l *limiter) reserve(out Outgoing, now time.Time, maxWait time.Duration) (wait time.Duration, ok bool) {
+	send := strings.HasPrefix(out.Method, "send")
+	if !send && !strings.HasPrefix(out.Method, "edit") {
+		return 0, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	wait = l.global.take(now)
+	if out.ChatID == 0 || !send {
+		if maxWait > 0 && wait > maxWait {
+			l.global.tokens++
+			return wait, false
+		}
+		return wait, true
+	}
+
+	chat, ok := l.chats[out.ChatID]
+	if !ok {
+		if len(l.chats) >= maxChatBuckets {
+			for id, b := range l.chats {
+				if b.idle(now) {
+					delete(l.chats, id)
+				}
+			}
+		}
+		// Отрицательные ID — группы и каналы
+		if out.ChatID < 0 {
+			chat = newBucket(groupRate, groupBurst)
+		} else {
+			chat = newBucket(privateRate, privateBurst)
+		}
+		l.chats[out.ChatID] = chat
+	}
+	wait = max(wait, chat.take(now))
+	if maxWait > 0 && wait > maxWait {
+		// Отказанный запрос не должен задерживать следующие
+		l.global.tokens++
+		chat.tokens++
+		return wait, false
+	}
+	return wait, true
+}
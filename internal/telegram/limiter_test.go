@@ -0,0 +1,63 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter()
+	now := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	private := Outgoing{Method: "sendMessage", ChatID: 42}
+	group := Outgoing{Method: "sendMessage", ChatID: -100}
+
+	for i := 0; i < privateBurst; i++ {
+		if wait, _ := l.reserve(private, now, 0); wait != 0 {
+			t.Fatalf("message %d within burst waits %v", i+1, wait)
+		}
+	}
+	if wait, _ := l.reserve(private, now, 0); wait != time.Second {
+		t.Errorf("message over burst waits %v, want 1s", wait)
+	}
+	if wait, _ := l.reserve(Outgoing{Method: "answerCallbackQuery"}, now, 0); wait != 0 {
+		t.Errorf("callback answer waits %v", wait)
+	}
+
+	for i := 0; i < groupBurst; i++ {
+		l.reserve(group, now, 0)
+	}
+	if wait, _ := l.reserve(group, now, 0); wait != 3*time.Second {
+		t.Errorf("group message over burst waits %v, want 3s", wait)
+	}
+	if wait, _ := l.reserve(group, now.Add(time.Minute), 0); wait != 0 {
+		t.Errorf("group message after a minute waits %v", wait)
+	}
+
+	// Правки сообщений в группе не расходуют лимит новых сообщений
+	later := now.Add(2 * time.Minute)
+	for i := 0; i < groupBurst+3; i++ {
+		if wait, _ := l.reserve(Outgoing{Method: "editMessageText", ChatID: -100, MessageID: 7}, later, 0); wait != 0 {
+			t.Fatalf("edit %d waits %v", i+1, wait)
+		}
+	}
+	if wait, _ := l.reserve(group, later, 0); wait != 0 {
+		t.Errorf("group message after edits waits %v", wait)
+	}
+}
+
+func TestLimiterMaxWait(t *testing.T) {
+	l := newLimiter()
+	now := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	group := Outgoing{Method: "sendMessage", ChatID: -100}
+
+	for i := 0; i < groupBurst; i++ {
+		l.reserve(group, now, maxInteractiveWait)
+	}
+	if wait, ok := l.reserve(group, now, maxInteractiveWait); ok || wait != 3*time.Second {
+		t.Errorf("reserve over max wait = %v, %v", wait, ok)
+	}
+	// Отказанный запрос не занял место в очереди
+	if wait, ok := l.reserve(group, now, 0); !ok || wait != 3*time.Second {
+		t.Errorf("next message waits %v, want 3s", wait)
+	}
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Members проверяет членство пользователей в чатах через getChatMember и
+// запоминает ответы на ttl, чтобы не спрашивать Telegram на каждую команду.
+// Ошибки, которые могут пройти, не кэшируются.
+type Members struct {
+	sender Sender
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[memberKey]memberEntry
+}
+
+type memberKey struct {
+	chatID, userID int64
+}
+
+type memberEntry struct {
+	member  bool
+	expires time.Time
+}
+
+func NewMembers(sender Sender, ttl time.Duration) *Members {
+	return &Members{
+		sender: sender,
+		ttl:    ttl,
+		now:    time.Now,
+		cache:  make(map[memberKey]memberEntry),
+	}
+}
+
+// IsMember сообщает, состоит ли пользователь в чате
+func (m *Members) IsMember(chatID, userID int64) (bool, error) {
+	key := memberKey{chatID, userID}
+	now := m.now()
+
+	m.mu.Lock()
+	entry, ok := m.cache[key]
+	m.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.member, nil
+	}
+
+	member, err := m.fetch(chatID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	m.cache[key] = memberEntry{member: member, expires: now.Add(m.ttl)}
+	m.mu.Unlock()
+	return member, nil
+}
+
+// Forget сбрасывает запомненный ответ, например после обновления chat_member
+func (m *Members) Forget(chatID, userID int64) {
+	m.mu.Lock()
+	delete(m.cache, memberKey{chatID, userID})
+	m.mu.Unlock()
+}
+
+func (m *Members) fetch(chatID, userID int64) (bool, error) {
+	resp, err := m.sender.Request(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if Permanent(err) {
+		// Пользователя нет в чате или он никогда в нём не был
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var member tgbotapi.ChatMember
+	if err := json.Unmarshal(resp.Result, &member); err != nil {
+		return false, err
+	}
+	return IsChatMember(member), nil
+}
+
+// IsChatMember сообщает, состоит ли участник в чате сейчас
+func IsChatMember(m tgbotapi.ChatMember) bool {
+	switch m.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return m.IsMember
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// tokenPattern совпадает с токеном бота в пути запроса к Bot API: /bot123456:AAH.../getMe
+var tokenPattern = regexp.MustCompile(`bot\d+:[\w-]+`)
+
+// RedactToken вырезает токен бота из текста ошибки. Ошибки сети в tgbotapi — это
+// *url.Error с полным URL запроса, а в нём токен; такие ошибки нельзя ни писать
+// в лог, ни сохранять, ни показывать как есть. errors.Is и errors.As по-прежнему
+// находят исходную ошибку.
+func RedactToken(err error) error {
+	if err == nil {
+		return nil
+	}
+	text := err.Error()
+	redacted := tokenPattern.ReplaceAllString(text, "bot<token>")
+	if redacted == text {
+		return err
+	}
+	return &redactedError{text: redacted, err: err}
+}
+
+type redactedError struct {
+	text string
+	err  error
+}
+
+func (e *redactedError) Error() string { return e.text }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// BotLogger передаёт сообщения tgbotapi в logger без токена бота. Сама
+// библиотека пишет ошибки getUpdates в stderr вместе с URL запроса.
+type BotLogger struct {
+	logger *slog.Logger
+}
+
+func NewBotLogger(logger *slog.Logger) *BotLogger {
+	return &BotLogger{logger: logger}
+}
+
+func (l *BotLogger) Println(v ...any) {
+	l.log(fmt.Sprintln(v...))
+}
+
+func (l *BotLogger) Printf(format string, v ...any) {
+	l.log(fmt.Sprintf(format, v...))
+}
+
+func (l *BotLogger) log(text string) {
+	text = tokenPattern.ReplaceAllString(text, "bot<token>")
+	l.logger.Warn("telegram-bot-api: " + strings.TrimSpace(text))
+}
@@ -0,0 +1,99 @@
+// Package telegram — общая работа с Bot API: интерфейс отправки, описание
+// запросов и клиент с повторами и ограничением частоты
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sender — часть Bot API, через которую бот отправляет сообщения и отвечает на
+// нажатия. Её реализуют *tgbotapi.BotAPI, Client и заглушки для тестов.
+type Sender interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+}
+
+// Outgoing — существенная часть запроса бота к Bot API
+type Outgoing struct {
+	Method    string   `json:"method"`
+	ChatID    int64    `json:"chat_id,omitempty"`
+	MessageID int      `json:"message_id,omitempty"` // сообщение, которое редактируется или удаляется
+	Text      string   `json:"text,omitempty"`
+	Buttons   []string `json:"buttons,omitempty"` // callback_data inline-кнопок
+}
+
+// Describe извлекает из запроса метод, чат, текст и кнопки. Для запросов,
+// которые бот не использует, заполняется только Method.
+func Describe(c tgbotapi.Chattable) Outgoing {
+	var out Outgoing
+	var markup interface{}
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		out = Outgoing{Method: "sendMessage", ChatID: v.ChatID, Text: v.Text}
+		markup = v.ReplyMarkup
+	case tgbotapi.DocumentConfig:
+		out = Outgoing{Method: "sendDocument", ChatID: v.ChatID, Text: v.Caption}
+		markup = v.ReplyMarkup
+	case tgbotapi.PhotoConfig:
+		out = Outgoing{Method: "sendPhoto", ChatID: v.ChatID, Text: v.Caption}
+		markup = v.ReplyMarkup
+	case tgbotapi.CallbackConfig:
+		out = Outgoing{Method: "answerCallbackQuery", Text: v.Text}
+	case tgbotapi.DeleteMessageConfig:
+		out = Outgoing{Method: "deleteMessage", ChatID: v.ChatID, MessageID: v.MessageID}
+	case tgbotapi.EditMessageTextConfig:
+		out = Outgoing{Method: "editMessageText", ChatID: v.ChatID, MessageID: v.MessageID, Text: v.Text}
+		markup = v.ReplyMarkup
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		out = Outgoing{Method: "editMessageReplyMarkup", ChatID: v.ChatID, MessageID: v.MessageID}
+		markup = v.ReplyMarkup
+	default:
+		out = Outgoing{Method: fmt.Sprintf("%T", c)}
+	}
+	out.Buttons = ButtonData(markup)
+	return out
+}
+
+// ButtonData возвращает callback_data всех кнопок inline-клавиатуры
+func ButtonData(markup interface{}) []string {
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	switch m := markup.(type) {
+	case tgbotapi.InlineKeyboardMarkup:
+		keyboard = &m
+	case *tgbotapi.InlineKeyboardMarkup:
+		keyboard = m
+	}
+	if keyboard == nil {
+		return nil
+	}
+
+	var data []string
+	for _, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				data = append(data, *button.CallbackData)
+			}
+		}
+	}
+	return data
+}
+
+// String — однострочное описание запроса для сравнения
+func (o Outgoing) String() string {
+	s := o.Method
+	if o.ChatID != 0 {
+		s += fmt.Sprintf(" chat=%d", o.ChatID)
+	}
+	if o.Text != "" {
+		text, _ := json.Marshal(o.Text)
+		s += " text=" + string(text)
+	}
+	if len(o.Buttons) > 0 {
+		s += " buttons=[" + strings.Join(o.Buttons, " ") + "]"
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package telegram
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Threads направляет новые сообщения в группу в нужную тему форума. Используемая
+// версия Bot API клиента не знает message_thread_id, поэтому сообщение
+// отправляется ответом: Telegram кладёт ответ в тему исходного сообщения.
+//
+// Пока обрабатывается обновление из группы (ReplyTo), бот отвечает на сообщение,
+// вызвавшее обработку. В остальное время сообщения в группу уходят в тему
+// threadID, если она задана. ReplyTo действует на все отправки через экземпляр,
+// поэтому то, что отправляется параллельно с обработкой обновлений, должно идти
+// через свой экземпляр.
+type Threads struct {
+	next     Sender
+	chatID   int64
+	threadID int
+
+	mu      sync.Mutex
+	replyTo int
+}
+
+// NewThreads оборачивает next для группы chatID; threadID — ID темы по
+// умолчанию или 0 для основной темы
+func NewThreads(next Sender, chatID int64, threadID int) *Threads {
+	return &Threads{next: next, chatID: chatID, threadID: threadID}
+}
+
+// ReplyTo отвечает на сообщение messageID группы до вызова возвращённой функции
+func (t *Threads) ReplyTo(messageID int) (done func()) {
+	t.mu.Lock()
+	t.replyTo = messageID
+	t.mu.Unlock()
+	return func() {
+		t.mu.Lock()
+		t.replyTo = 0
+		t.mu.Unlock()
+	}
+}
+
+func (t *Threads) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return t.next.Send(t.thread(c))
+}
+
+func (t *Threads) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return t.next.Request(t.thread(c))
+}
+
+// thread делает новое сообщение в группу ответом; остальные запросы не меняются
+func (t *Threads) thread(c tgbotapi.Chattable) tgbotapi.Chattable {
+	t.mu.Lock()
+	replyTo := t.replyTo
+	t.mu.Unlock()
+	if replyTo == 0 {
+		replyTo = t.threadID
+	}
+	if replyTo == 0 {
+		return c
+	}
+
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		t.reply(&c.BaseChat, replyTo)
+		return c
+	case tgbotapi.DocumentConfig:
+		t.reply(&c.BaseChat, replyTo)
+		return c
+	case tgbotapi.PhotoConfig:
+		t.reply(&c.BaseChat, replyTo)
+		return c
+	default:
+		return c
+	}
+}
+
+func (t *Threads) reply(chat *tgbotapi.BaseChat, replyTo int) {
+	if chat.ChatID != t.chatID || chat.ReplyToMessageID != 0 {
+		return
+	}
+	chat.ReplyToMessageID = replyTo
+	// Исходное сообщение могли удалить — тогда пусть уходит без ответа
+	chat.AllowSendingWithoutReply = true
+}
@@ -0,0 +1,27 @@
+package telegramtest
+
+import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+// FlakySender отвечает ошибками из Errs по очереди, а когда они кончились — успехом
+// с MessageID 42. Calls считает все вызовы, Sent хранит успешно отправленное.
+type FlakySender struct {
+	Errs  []error
+	Calls int
+	Sent  []tgbotapi.Chattable
+}
+
+func (s *FlakySender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	s.Calls++
+	if len(s.Errs) > 0 {
+		err := s.Errs[0]
+		s.Errs = s.Errs[1:]
+		return tgbotapi.Message{}, err
+	}
+	s.Sent = append(s.Sent, c)
+	return tgbotapi.Message{MessageID: 42}, nil
+}
+
+func (s *FlakySender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	_, err := s.Send(c)
+	return &tgbotapi.APIResponse{Ok: err == nil}, err
+}
@@ -0,0 +1,335 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/telegram"
+)
+
+// Request — запрос бота к Server
+type Request struct {
+	Method string
+	Form   url.Values
+}
+
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Form.Get("chat_id"), 10, 64)
+	return id
+}
+
+// Text — текст сообщения, подсказки callback или подпись к файлу
+func (r Request) Text() string {
+	if r.Form.Has("text") {
+		return r.Form.Get("text")
+	}
+	return r.Form.Get("caption")
+}
+
+// ButtonData возвращает callback_data inline-кнопок из reply_markup
+func (r Request) ButtonData() []string {
+	return telegram.ButtonData(inlineKeyboard(r.Form.Get("reply_markup")))
+}
+
+func inlineKeyboard(raw string) *tgbotapi.InlineKeyboardMarkup {
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	if raw == "" || json.Unmarshal([]byte(raw), &keyboard) != nil || keyboard.InlineKeyboard == nil {
+		return nil
+	}
+	return &keyboard
+}
+
+// Server — локальная заглушка Bot API для сквозных тестов. Она поддерживает
+// методы, которые вызывает бот, выдаёт обновления через getUpdates и хранит
+// отправленные ботом сообщения, чтобы по ним можно было нажимать кнопки.
+type Server struct {
+	URL string
+
+	srv    *httptest.Server
+	token  string
+	closed chan struct{}
+
+	mu            sync.Mutex
+	changed       chan struct{}
+	updates       []tgbotapi.Update
+	requests      []Request
+	messages      map[int]*tgbotapi.Message
+	lastUpdateID  int
+	lastMessageID int
+}
+
+// NewServer запускает заглушку, которая принимает запросы только с токеном token.
+// Адрес URL передаётся боту как TELEGRAM_API_URL.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:    token,
+		closed:   make(chan struct{}),
+		changed:  make(chan struct{}),
+		messages: make(map[int]*tgbotapi.Message),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close останавливает заглушку, прерывая ожидающие getUpdates
+func (s *Server) Close() {
+	close(s.closed)
+	s.srv.Close()
+}
+
+// Requests возвращает все запросы бота, кроме getUpdates
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// SendMessage доставляет боту сообщение пользователя userID в чате chatID
+func (s *Server) SendMessage(chatID, userID int64, text string) {
+	msg := Message(chatID, userID, text)
+	msg.Date = int(time.Now().Unix())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastMessageID++
+	msg.MessageID = s.lastMessageID
+	s.pushUpdate(tgbotapi.Update{Message: msg})
+}
+
+// PressButton нажимает кнопку с данными data под последним сообщением бота в чате,
+// где такая кнопка есть
+func (s *Server) PressButton(chatID, userID int64, data string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var target *tgbotapi.Message
+	for _, msg := range s.messages {
+		if msg.Chat.ID != chatID || (target != nil && msg.MessageID < target.MessageID) {
+			continue
+		}
+		for _, d := range telegram.ButtonData(msg.ReplyMarkup) {
+			if d == data {
+				target = msg
+			}
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no message in chat %d has button %q", chatID, data)
+	}
+
+	message := *target
+	s.pushUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      strconv.Itoa(s.lastUpdateID + 1),
+		From:    &tgbotapi.User{ID: userID, FirstName: "User"},
+		Message: &message,
+		Data:    data,
+	}})
+	return nil
+}
+
+// WaitFor ждёт запроса method с текстом, содержащим text, среди запросов после
+// первых from. Возвращает запрос и его номер.
+func (s *Server) WaitFor(from int, method, text string, timeout time.Duration) (Request, int, error) {
+	deadline := time.After(timeout)
+	for {
+		s.mu.Lock()
+		for i := from; i < len(s.requests); i++ {
+			if r := s.requests[i]; r.Method == method && strings.Contains(r.Text(), text) {
+				s.mu.Unlock()
+				return r, i, nil
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return Request{}, 0, fmt.Errorf("timed out waiting for %s containing %q", method, text)
+		}
+	}
+}
+
+// Step — шаг сценария: действие пользователя и ответ, которого ждём от бота.
+// Действие — сообщение Text или нажатие кнопки Press; Method по умолчанию sendMessage.
+type Step struct {
+	Text   string
+	Press  string
+	Expect string
+	Method string
+}
+
+// Play проигрывает диалог пользователя userID в чате chatID и возвращает
+// ошибку на первом шаге, ответа на который не было за timeout
+func (s *Server) Play(chatID, userID int64, steps []Step, timeout time.Duration) error {
+	for i, step := range steps {
+		from := len(s.Requests())
+		if step.Press != "" {
+			if err := s.PressButton(chatID, userID, step.Press); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		} else {
+			s.SendMessage(chatID, userID, step.Text)
+		}
+
+		method := step.Method
+		if method == "" {
+			method = "sendMessage"
+		}
+		if _, _, err := s.WaitFor(from, method, step.Expect, timeout); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// pushUpdate ставит обновление в очередь; вызывается под s.mu
+func (s *Server) pushUpdate(update tgbotapi.Update) {
+	s.lastUpdateID++
+	update.UpdateID = s.lastUpdateID
+	s.updates = append(s.updates, update)
+	s.notify()
+}
+
+// notify будит ожидающих getUpdates и WaitFor; вызывается под s.mu
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != s.token {
+		writeResponse(w, http.StatusUnauthorized, nil, "Unauthorized")
+		return
+	}
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
+		writeResponse(w, http.StatusBadRequest, nil, "Bad Request: "+err.Error())
+		return
+	}
+
+	if method == "getUpdates" {
+		s.getUpdates(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{Method: method, Form: r.Form})
+	defer s.notify()
+
+	switch method {
+	case "getMe":
+		writeResponse(w, http.StatusOK, tgbotapi.User{ID: 1, IsBot: true, FirstName: "Test", UserName: "test_bot"}, "")
+	case "sendMessage", "sendDocument", "sendPhoto":
+		writeResponse(w, http.StatusOK, s.storeMessage(r.Form), "")
+	case "editMessageText", "editMessageReplyMarkup":
+		msg, ok := s.messages[formInt(r.Form, "message_id")]
+		if !ok {
+			writeResponse(w, http.StatusBadRequest, nil, "Bad Request: message to edit not found")
+			return
+		}
+		if method == "editMessageText" {
+			msg.Text = r.Form.Get("text")
+		}
+		msg.ReplyMarkup = inlineKeyboard(r.Form.Get("reply_markup"))
+		writeResponse(w, http.StatusOK, msg, "")
+	case "deleteMessage":
+		if _, ok := s.messages[formInt(r.Form, "message_id")]; !ok {
+			writeResponse(w, http.StatusBadRequest, nil, "Bad Request: message to delete not found")
+			return
+		}
+		delete(s.messages, formInt(r.Form, "message_id"))
+		writeResponse(w, http.StatusOK, true, "")
+	case "answerCallbackQuery", "deleteWebhook":
+		writeResponse(w, http.StatusOK, true, "")
+	default:
+		writeResponse(w, http.StatusNotFound, nil, "Not Found: method "+method+" is not supported by the fake server")
+	}
+}
+
+// getUpdates отдаёт обновления начиная с offset, ожидая новых не дольше timeout секунд
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request) {
+	offset := formInt(r.Form, "offset")
+	deadline := time.After(time.Duration(formInt(r.Form, "timeout")) * time.Second)
+
+	for {
+		s.mu.Lock()
+		var pending []tgbotapi.Update
+		for _, u := range s.updates {
+			if u.UpdateID >= offset {
+				pending = append(pending, u)
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(pending) > 0 {
+			writeResponse(w, http.StatusOK, pending, "")
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			writeResponse(w, http.StatusOK, []tgbotapi.Update{}, "")
+			return
+		case <-s.closed:
+			writeResponse(w, http.StatusOK, []tgbotapi.Update{}, "")
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// storeMessage запоминает отправленное ботом сообщение; вызывается под s.mu
+func (s *Server) storeMessage(form url.Values) *tgbotapi.Message {
+	s.lastMessageID++
+	msg := &tgbotapi.Message{
+		MessageID:   s.lastMessageID,
+		Date:        int(time.Now().Unix()),
+		Chat:        chat(formInt64(form, "chat_id")),
+		Text:        form.Get("text"),
+		Caption:     form.Get("caption"),
+		ReplyMarkup: inlineKeyboard(form.Get("reply_markup")),
+	}
+	s.messages[msg.MessageID] = msg
+	return msg
+}
+
+func formInt(form url.Values, key string) int {
+	n, _ := strconv.Atoi(form.Get(key))
+	return n
+}
+
+func formInt64(form url.Values, key string) int64 {
+	n, _ := strconv.ParseInt(form.Get(key), 10, 64)
+	return n
+}
+
+func writeResponse(w http.ResponseWriter, status int, result any, description string) {
+	resp := map[string]any{"ok": status == http.StatusOK}
+	if status == http.StatusOK {
+		resp["result"] = result
+	} else {
+		resp["error_code"] = status
+		resp["description"] = description
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
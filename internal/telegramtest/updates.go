@@ -0,0 +1,50 @@
+// Package telegramtest содержит заглушки Telegram Bot API для тестов
+package telegramtest
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Message собирает входящее сообщение; текст, начинающийся с "/", размечается как команда
+func Message(chatID, userID int64, text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: userID, FirstName: "User"},
+		Chat:      chat(chatID),
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = []tgbotapi.MessageEntity{{
+			Type:   "bot_command",
+			Offset: 0,
+			Length: len(utf16.Encode([]rune(command))),
+		}}
+	}
+	return msg
+}
+
+// Callback собирает нажатие inline-кнопки с данными data под сообщением messageText
+func Callback(chatID, userID int64, messageText, data string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:   "callback",
+		From: &tgbotapi.User{ID: userID, FirstName: "User"},
+		Message: &tgbotapi.Message{
+			MessageID: 100,
+			Chat:      chat(chatID),
+			Text:      messageText,
+		},
+		Data: data,
+	}
+}
+
+// chat — личный чат для положительного ID и супергруппа для отрицательного, как в Telegram
+func chat(chatID int64) *tgbotapi.Chat {
+	if chatID < 0 {
+		return &tgbotapi.Chat{ID: chatID, Type: "supergroup"}
+	}
+	return &tgbotapi.Chat{ID: chatID, Type: "private"}
+}
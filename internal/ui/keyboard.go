@@ -23,6 +23,10 @@ func AdminMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
             tgbotapi.NewKeyboardButton("/admin_list"),
             tgbotapi.NewKeyboardButton("/admin_stats"),
         ),
+        tgbotapi.NewKeyboardButtonRow(
+            tgbotapi.NewKeyboardButton("/admin_today"),
+            tgbotapi.NewKeyboardButton("/admin_tomorrow"),
+        ),
         tgbotapi.NewKeyboardButtonRow(
             tgbotapi.NewKeyboardButton("/admin_help"),
             tgbotapi.NewKeyboardButton("↩️ Главное меню"),
@@ -32,6 +36,18 @@ func AdminMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
     return keyboard
 }
 
+// DoctorMenuKeyboard — меню врача: только его собственное расписание
+func DoctorMenuKeyboard() tgbotapi.ReplyKeyboardMarkup {
+    keyboard := tgbotapi.NewReplyKeyboard(
+        tgbotapi.NewKeyboardButtonRow(
+            tgbotapi.NewKeyboardButton("👨‍⚕️ Мой день"),
+            tgbotapi.NewKeyboardButton("📅 Моя неделя"),
+        ),
+    )
+    keyboard.ResizeKeyboard = true
+    return keyboard
+}
+
 func ServiceKeyboard() tgbotapi.ReplyKeyboardMarkup {
     keyboard := tgbotapi.NewReplyKeyboard(
         tgbotapi.NewKeyboardButtonRow(
@@ -49,4 +65,15 @@ func ServiceKeyboard() tgbotapi.ReplyKeyboardMarkup {
     )
     keyboard.ResizeKeyboard = true
     return keyboard
-}
\ No newline at end of file
+}
+// KnownPatientKeyboard — подтвердить сохранённые имя и телефон или ввести заново
+func KnownPatientKeyboard() tgbotapi.ReplyKeyboardMarkup {
+    keyboard := tgbotapi.NewReplyKeyboard(
+        tgbotapi.NewKeyboardButtonRow(
+            tgbotapi.NewKeyboardButton("Да"),
+            tgbotapi.NewKeyboardButton("Изменить"),
+        ),
+    )
+    keyboard.ResizeKeyboard = true
+    return keyboard
+}
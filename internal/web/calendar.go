@@ -0,0 +1,60 @@
+package web
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/REmakerzz/dental-clinic-bot/internal/calendar"
+	"github.com/REmakerzz/dental-clinic-bot/internal/service"
+)
+
+// CalendarFeedPath — шаблон пути календаря врача; токен выдаётся в /admin_doctors
+const CalendarFeedPath = "GET /calendar/{token}"
+
+// CalendarFeedURL — ссылка на календарь врача для подписки
+func CalendarFeedURL(publicURL, token string) string {
+	return publicURL + "/calendar/" + token + ".ics"
+}
+
+// CalendarFeedHandler отдаёт приёмы врача в формате iCalendar
+func CalendarFeedHandler(doctors *service.DoctorService, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSuffix(r.PathValue("token"), ".ics")
+
+		doctor, bookings, err := doctors.GetFeed(token)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.NotFound(w, r)
+				return
+			}
+			logger.Error("failed to build calendar feed", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		events := make([]calendar.Event, 0, len(bookings))
+		for _, b := range bookings {
+			events = append(events, calendar.Event{
+				Booking:     b,
+				Summary:     b.Service + " — " + b.Name,
+				Description: "Пациент: " + b.Name + "\nТелефон: " + b.Phone + "\nЗаявка #" + strconv.Itoa(b.ID),
+			})
+		}
+
+		var buf bytes.Buffer
+		if err := calendar.WriteCalendar(&buf, "Приёмы: "+doctor.Name, events); err != nil {
+			logger.Error("failed to write calendar feed", "doctor_id", doctor.ID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const readyCheckTimeout = 5 * time.Second
+
+// Check — проверка готовности зависимости; nil означает, что она доступна
+type Check struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// HealthHandler отвечает 200, пока процесс жив и обслуживает HTTP
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// ReadyHandler выполняет проверки и отвечает 503, если хотя бы одна не прошла.
+// В теле ответа — только ok или fail для каждой проверки: сервер публичный,
+// а текст ошибки может раскрыть адреса и токены. Подробности пишутся в лог.
+func ReadyHandler(logger *slog.Logger, checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+		defer cancel()
+
+		status := http.StatusOK
+		results := make(map[string]string, len(checks))
+		for _, c := range checks {
+			if err := c.Fn(ctx); err != nil {
+				status = http.StatusServiceUnavailable
+				results[c.Name] = "fail"
+				logger.Warn("readiness check failed", "check", c.Name, "error", err)
+			} else {
+				results[c.Name] = "ok"
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(results)
+	})
+}
@@ -0,0 +1,66 @@
+// Package web — встроенный HTTP-сервер бота
+package web
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type Server struct {
+	mux      *http.ServeMux
+	srv      *http.Server
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+}
+
+// NewServer создаёт сервер; при заданных certFile и keyFile он работает по HTTPS
+func NewServer(addr, certFile, keyFile string, logger *slog.Logger) *Server {
+	mux := http.NewServeMux()
+	return &Server{
+		mux:      mux,
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+		srv: &http.Server{
+			Addr:              addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+			ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		},
+	}
+}
+
+// Handle регистрирует обработчик по шаблону http.ServeMux
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Run обслуживает запросы до отмены ctx
+func (s *Server) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := s.srv.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("HTTP server shutdown", "error", err)
+		}
+	}()
+
+	var err error
+	if s.certFile != "" {
+		s.logger.Info("HTTPS server listening", "addr", s.srv.Addr)
+		err = s.srv.ListenAndServeTLS(s.certFile, s.keyFile)
+	} else {
+		s.logger.Info("HTTP server listening", "addr", s.srv.Addr)
+		err = s.srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("HTTP server failed", "error", err)
+	}
+}
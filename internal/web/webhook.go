@@ -0,0 +1,46 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretTokenHeader — заголовок, в котором Telegram передаёт secret_token из setWebhook
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// WebhookHandler принимает обновления от Telegram и передаёт их в updates.
+// Запросы без правильного секрета отклоняются.
+func WebhookHandler(secret string, updates chan<- tgbotapi.Update, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		got := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+			logger.Warn("webhook request with wrong secret token", "remote_addr", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+			logger.Warn("bad webhook update", "error", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case updates <- update:
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			// Telegram повторит доставку, если не получит ответ 200
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		}
+	})
+}